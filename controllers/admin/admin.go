@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"gin-auth-mongo/models/requests"
+	adminService "gin-auth-mongo/services/admin"
+	"gin-auth-mongo/utils/response"
+	"gin-auth-mongo/utils/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// [GET] get the signing keys and their states
+func GetSigningKeys(c *gin.Context) {
+	response.SuccessWithData(c, adminService.GetSigningKeys())
+}
+
+// [POST] rotate the signing keys
+func RotateSigningKeys(c *gin.Context) {
+	var request requests.RotateKeysRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	keys, err := adminService.RotateSigningKeys(request.Immediate, request.Algorithm)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, keys)
+}
+
+// [GET] get the service accounts
+func GetServiceAccounts(c *gin.Context) {
+	accounts, err := adminService.GetServiceAccounts()
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, accounts)
+}
+
+// [POST] create a service account, the secret is only shown in this response
+func CreateServiceAccount(c *gin.Context) {
+	var request requests.ServiceAccountCreateRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	account, err := adminService.CreateServiceAccount(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, account)
+}
+
+// [PUT] update the name, the scopes or the disabled flag of a service account
+func UpdateServiceAccount(c *gin.Context) {
+	var request requests.ServiceAccountUpdateRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	account, err := adminService.UpdateServiceAccount(c.Param("id"), &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, account)
+}
+
+// [POST] rotate the secret of a service account, the new secret is only shown in this response
+func RotateServiceAccountSecret(c *gin.Context) {
+	account, err := adminService.RotateServiceAccountSecret(c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, account)
+}
+
+// [DELETE] delete a service account
+func DeleteServiceAccount(c *gin.Context) {
+	err := adminService.DeleteServiceAccount(c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [GET] get the users, paginated and filtered
+func GetUsers(c *gin.Context) {
+	var request requests.AdminUserListRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	users, err := adminService.GetUsers(&request)
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, users)
+}
+
+// [GET] get a user with its sessions and security events
+func GetUser(c *gin.Context) {
+	user, err := adminService.GetUser(c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, user)
+}
+
+// [POST] require the user to reset the password, a reset link is sent by email and the sessions and personal access tokens are revoked
+func ForceUserPasswordReset(c *gin.Context) {
+	err := adminService.ForceUserPasswordReset(c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [DELETE] sign the user out of all the devices
+func RevokeUserSessions(c *gin.Context) {
+	err := adminService.RevokeUserSessions(c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [PUT] enable or disable a user
+func UpdateUserStatus(c *gin.Context) {
+	var request requests.AdminUpdateUserStatusRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	adminID := c.GetString("userID")
+
+	user, err := adminService.UpdateUserStatus(adminID, c.Param("id"), &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, user)
+}
+
+// [PUT] update the premium of a user
+func UpdateUserPremium(c *gin.Context) {
+	var request requests.AdminUpdateUserPremiumRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	user, err := adminService.UpdateUserPremium(c.Param("id"), &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, user)
+}
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"net/http"
+
+	"gin-auth-mongo/graph/model"
 	"gin-auth-mongo/models/requests"
 	authService "gin-auth-mongo/services/auth"
 	"gin-auth-mongo/utils/jwt"
@@ -10,6 +13,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// respond the login and record the client of the new session
+// no token is issued while the login is waiting for the second factor
+func loginSuccess(c *gin.Context, loginResponse *model.LoginResponse) {
+	if loginResponse.Token != nil {
+		authService.RecordSessionClient(loginResponse.Token.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	}
+	response.SuccessWithData(c, loginResponse)
+}
+
 // [POST] register
 func UserEmailRegisterWithLink(c *gin.Context) {
 	var request requests.EmailRegisterLinkRequest
@@ -44,6 +56,23 @@ func UserEmailRegisterWithCode(c *gin.Context) {
 	response.Success(c)
 }
 
+// [POST] resend the registration code
+func UserEmailRegisterWithCodeResend(c *gin.Context) {
+	var request requests.EmailCodeResendRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := authService.UserEmailRegisterWithCodeResend(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
 // [POST] verify email register
 func UserEmailRegisterWithLinkVerify(c *gin.Context) {
 	var request requests.EmailRegisterLinkVerifyRequest
@@ -106,16 +135,13 @@ func UserEmailLoginWithPassword(c *gin.Context) {
 		return
 	}
 
-	user, token, err := authService.UserEmailLoginWithPassword(&request)
+	loginResponse, err := authService.UserEmailLoginWithPassword(&request)
 	if err != nil {
 		response.BadRequestWithMessage(c, err.Error())
 		return
 	}
 
-	response.SuccessWithData(c, map[string]interface{}{
-		"user":  user,
-		"token": token,
-	})
+	loginSuccess(c, loginResponse)
 }
 
 // [POST] login with username and password
@@ -126,16 +152,211 @@ func UserUsernameLoginWithPassword(c *gin.Context) {
 		return
 	}
 
-	user, token, err := authService.UserUsernameLoginWithPassword(&request)
+	loginResponse, err := authService.UserUsernameLoginWithPassword(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
+}
+
+// [POST] send sign in link to email
+func UserEmailLoginWithLink(c *gin.Context) {
+	var request requests.EmailLoginLinkRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := authService.UserEmailLoginWithLink(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [POST] login with the link sent to email
+func UserEmailLoginWithLinkVerify(c *gin.Context) {
+	var request requests.EmailLoginLinkVerifyRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	loginResponse, err := authService.UserEmailLoginLinkVerify(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
+}
+
+// [POST] send sign in code to email
+func UserEmailLoginWithCode(c *gin.Context) {
+	var request requests.EmailLoginCodeRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := authService.UserEmailLoginWithCode(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [POST] login with the code sent to email
+func UserEmailLoginWithCodeVerify(c *gin.Context) {
+	var request requests.EmailLoginCodeVerifyRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	loginResponse, err := authService.UserEmailLoginCodeVerify(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
+}
+
+// [POST] send sign in code to phone
+func UserPhoneLoginWithCode(c *gin.Context) {
+	var request requests.PhoneLoginCodeRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := authService.UserPhoneLoginWithCode(&request)
 	if err != nil {
 		response.BadRequestWithMessage(c, err.Error())
 		return
 	}
 
-	response.SuccessWithData(c, map[string]interface{}{
-		"user":  user,
-		"token": token,
-	})
+	response.Success(c)
+}
+
+// [POST] login with the code sent to phone
+func UserPhoneLoginWithCodeVerify(c *gin.Context) {
+	var request requests.PhoneLoginCodeVerifyRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	loginResponse, err := authService.UserPhoneLoginCodeVerify(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
+}
+
+// [GET] redirect to the authorization page of the oauth provider
+func UserOAuthLoginBegin(c *gin.Context) {
+	authURL, err := authService.CreateOAuthAuthorizationURL(c.Param("provider"), "")
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// [GET] the oauth provider redirects here, then redirect to the frontend
+func UserOAuthCallback(c *gin.Context) {
+	redirectURL := authService.UserOAuthCallback(c.Param("provider"), c.Query("state"), c.Query("code"), c.Query("error"))
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// [POST] login with the flow id from the oauth callback
+func UserOAuthLoginVerify(c *gin.Context) {
+	var request requests.OAuthLoginVerifyRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	loginResponse, err := authService.UserOAuthLoginVerify(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
+}
+
+// [POST] verify the second factor after login
+func UserLoginTwoFactorVerify(c *gin.Context) {
+	var request requests.TwoFactorLoginVerifyRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	loginResponse, err := authService.UserLoginTwoFactorVerify(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
+}
+
+// [POST] unlock the account locked by too many failed logins
+func UnlockAccount(c *gin.Context) {
+	var request requests.UnlockAccountRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := authService.UnlockAccount(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [POST] begin passkey login
+func UserPasskeyLoginBegin(c *gin.Context) {
+	options, err := authService.UserPasskeyLoginBegin()
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, options)
+}
+
+// [POST] finish passkey login
+func UserPasskeyLoginFinish(c *gin.Context) {
+	var request requests.PasskeyLoginFinishRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	loginResponse, err := authService.UserPasskeyLoginFinish(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	loginSuccess(c, loginResponse)
 }
 
 // [POST] reset email password with link
@@ -172,6 +393,23 @@ func UserEmailResetPasswordWithCode(c *gin.Context) {
 	response.Success(c)
 }
 
+// [POST] resend the password reset code
+func UserEmailResetPasswordWithCodeResend(c *gin.Context) {
+	var request requests.EmailCodeResendRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := authService.UserEmailResetPasswordWithCodeResend(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
 // [POST] verify email password reset with link
 func UserEmailResetPasswordWithLinkVerify(c *gin.Context) {
 	var request requests.EmailPasswordResetLinkVerifyRequest
@@ -224,19 +462,20 @@ func CheckUserEmailResetPasswordLinkExpired(c *gin.Context) {
 	response.SuccessWithData(c, info)
 }
 
-// [GET] use refresh token to refresh access token
+// [POST] use refresh token to refresh access token, the refresh token is rotated
 func RefreshToken(c *gin.Context) {
-	token, err := jwt.GetTokenFromHeader(c)
-	if err != nil {
-		response.BadRequestWithMessage(c, err.Error())
+	var request requests.RefreshTokenRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
 		return
 	}
 
-	accessToken, err := authService.RefreshToken(token)
+	accessToken, err := authService.RefreshToken(&request)
 	if err != nil {
 		response.BadRequestWithMessage(c, err.Error())
 		return
 	}
+	authService.RecordSessionClient(accessToken.RefreshToken, c.ClientIP(), c.Request.UserAgent())
 	response.SuccessWithData(c, accessToken)
 }
 
@@ -0,0 +1,284 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"gin-auth-mongo/models/requests"
+	oidcService "gin-auth-mongo/services/oidc"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/jwkmanager"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/response"
+	"gin-auth-mongo/utils/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/square/go-jose/v3"
+)
+
+// the oauth2 endpoints respond in the oauth2 format instead of the unified response
+func oauthError(c *gin.Context, err *oidcService.OAuthError) {
+	c.JSON(err.Status, err)
+}
+
+// [GET] openid connect discovery document
+func Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(consts.WELL_KNOWN_CACHE_MAX_AGE))
+	c.JSON(http.StatusOK, oidcService.Discovery())
+}
+
+// [GET] issuer metadata of the access tokens of this server
+func IssuerMetadata(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(consts.WELL_KNOWN_CACHE_MAX_AGE))
+	c.JSON(http.StatusOK, oidcService.IssuerMetadata())
+}
+
+// [GET] the public keys to verify the id tokens and the access tokens
+// the etag is the hash of the key set, so it changes when the keys are rotated
+func JWKS(c *gin.Context) {
+	publicJWKs, err := jwkmanager.GetPublicJWKs()
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: publicJWKs})
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(consts.JWKS_CACHE_MAX_AGE)+", must-revalidate")
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// [GET] validate the authorization request and redirect to the consent screen
+func Authorize(c *gin.Context) {
+	var request requests.OIDCAuthorizeRequest
+
+	if err := c.ShouldBindQuery(&request); err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	redirectURL, err := oidcService.Authorize(&request)
+	if err != nil {
+		// the client or the redirect uri is invalid, MUST NOT redirect
+		response.BadRequestWithMessage(c, err.Description)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// [GET] get the data of the consent screen
+func GetConsent(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	consent, err := oidcService.GetConsent(userID, c.Param("requestId"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, consent)
+}
+
+// [POST] approve or deny the authorization request, return the url to redirect to
+func SubmitConsent(c *gin.Context) {
+	var request requests.OIDCConsentRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	redirectURL, err := oidcService.SubmitConsent(userID, c.Param("requestId"), &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, gin.H{"redirectUri": redirectURL})
+}
+
+// [POST] exchange the authorization code for the tokens, or issue the access token of a service account
+func Token(c *gin.Context) {
+	var request requests.OIDCTokenRequest
+
+	if err := c.ShouldBind(&request); err != nil {
+		oauthError(c, &oidcService.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request"})
+		return
+	}
+
+	// client_secret_basic
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		request.ClientId = clientID
+		request.ClientSecret = clientSecret
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	token, err := oidcService.Token(&request)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// [POST] issue the access token of a service account with the client credentials grant
+func ClientCredentialsToken(c *gin.Context) {
+	var request requests.OIDCTokenRequest
+
+	if err := c.ShouldBind(&request); err != nil {
+		oauthError(c, &oidcService.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request"})
+		return
+	}
+
+	// client_secret_basic
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		request.ClientId = clientID
+		request.ClientSecret = clientSecret
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	token, err := oidcService.ClientCredentialsToken(&request)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// [POST] tell the resource server whether the token is active
+func Introspect(c *gin.Context) {
+	var request requests.OIDCTokenIntrospectRequest
+
+	if err := c.ShouldBind(&request); err != nil {
+		oauthError(c, &oidcService.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request"})
+		return
+	}
+
+	// client_secret_basic
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		request.ClientId = clientID
+		request.ClientSecret = clientSecret
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	introspection, err := oidcService.Introspect(&request)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, introspection)
+}
+
+// [POST] revoke the access token or the refresh token
+func Revoke(c *gin.Context) {
+	var request requests.OIDCTokenRevokeRequest
+
+	if err := c.ShouldBind(&request); err != nil {
+		oauthError(c, &oidcService.OAuthError{Status: http.StatusBadRequest, Code: "invalid_request"})
+		return
+	}
+
+	// client_secret_basic
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		request.ClientId = clientID
+		request.ClientSecret = clientSecret
+	}
+
+	if err := oidcService.Revoke(&request); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// [GET/POST] get the claims of the user with the access token
+func UserInfo(c *gin.Context) {
+	token, err := jwt.GetTokenFromHeader(c)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		oauthError(c, &oidcService.OAuthError{Status: http.StatusUnauthorized, Code: "invalid_token"})
+		return
+	}
+
+	userInfo, oauthErr := oidcService.UserInfo(token)
+	if oauthErr != nil {
+		c.Header("WWW-Authenticate", `Bearer error="`+oauthErr.Code+`"`)
+		oauthError(c, oauthErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, userInfo)
+}
+
+// [POST] register a client
+func CreateClient(c *gin.Context) {
+	var request requests.OIDCClientCreateRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	client, err := oidcService.CreateOAuthClient(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, client)
+}
+
+// [GET] get the registered clients
+func GetClients(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	clients, err := oidcService.GetOAuthClients(userID)
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, clients)
+}
+
+// [DELETE] delete the client
+func DeleteClient(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	err := oidcService.DeleteOAuthClient(userID, c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
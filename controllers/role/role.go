@@ -0,0 +1,83 @@
+package role
+
+import (
+	"gin-auth-mongo/models/requests"
+	roleService "gin-auth-mongo/services/role"
+	"gin-auth-mongo/utils/response"
+	"gin-auth-mongo/utils/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// [GET] get the roles
+func GetRoles(c *gin.Context) {
+	roles, err := roleService.GetRoles()
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, roles)
+}
+
+// [POST] create a role
+func CreateRole(c *gin.Context) {
+	var request requests.CreateRoleRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	role, err := roleService.CreateRole(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, role)
+}
+
+// [PUT] update the description and the permissions of a role
+func UpdateRole(c *gin.Context) {
+	var request requests.UpdateRoleRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	role, err := roleService.UpdateRole(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, role)
+}
+
+// [DELETE] delete a role
+func DeleteRole(c *gin.Context) {
+	err := roleService.DeleteRole(c.Param("name"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [PUT] replace the roles of a user
+func SetUserRoles(c *gin.Context) {
+	var request requests.SetUserRolesRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	err := roleService.SetUserRoles(&request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
@@ -136,8 +136,11 @@ func UserLogoutCurrentDevice(c *gin.Context) {
 	}
 
 	userID := c.GetString("userID")
+	jti := c.GetString("jti")
+	familyID := c.GetString("fid")
+	expiredAtUnix := c.GetInt64("expiredAtUnix")
 
-	err := userServices.UserLogoutCurrentDevice(userID, &request)
+	err := userServices.UserLogoutCurrentDevice(userID, jti, familyID, expiredAtUnix, &request)
 	if err != nil {
 		response.BadRequestWithMessage(c, err.Error())
 		return
@@ -159,6 +162,77 @@ func UserLogoutAllDevice(c *gin.Context) {
 	response.Success(c)
 }
 
+// [GET] get the sessions, the session of the current access token is marked as current
+func GetUserSessions(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	sessions, err := userServices.GetUserSessions(userID, c.GetString("fid"))
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, sessions)
+}
+
+// [DELETE] sign out a session
+func RevokeUserSession(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	err := userServices.RevokeUserSession(userID, c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [GET] get the personal access tokens, the tokens themselves are not returned
+func GetPersonalAccessTokens(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	tokens, err := userServices.GetPersonalAccessTokens(userID)
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, tokens)
+}
+
+// [POST] create a personal access token, the token is only shown in this response
+func CreatePersonalAccessToken(c *gin.Context) {
+	var request requests.CreatePersonalAccessTokenRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	token, err := userServices.CreatePersonalAccessToken(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, token)
+}
+
+// [DELETE] revoke a personal access token
+func DeletePersonalAccessToken(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	err := userServices.DeletePersonalAccessToken(userID, c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
 // [DELETE] delete user account
 func DeleteUserAccount(c *gin.Context) {
 	userID := c.GetString("userID")
@@ -175,3 +249,208 @@ func DeleteUserAccount(c *gin.Context) {
 	}
 	response.Success(c)
 }
+
+// [POST] send a verification code to the new phone number
+func UserUpdatePhone(c *gin.Context) {
+	var request requests.UpdatePhoneRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	err := userServices.UserUpdatePhone(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [POST] verify the code and bind the phone number
+func UserVerifyPhone(c *gin.Context) {
+	var request requests.PhoneVerifyRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	err := userServices.UserVerifyPhone(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [POST] generate a totp secret for two factor authentication
+func UserTotpSetup(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	setup, err := userServices.UserTotpSetup(userID)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, setup)
+}
+
+// [POST] confirm the totp secret with a code and enable two factor authentication, return the recovery codes
+func UserTotpConfirm(c *gin.Context) {
+	var request requests.TotpConfirmRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	recoveryCodes, err := userServices.UserTotpConfirm(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, recoveryCodes)
+}
+
+// [POST] disable two factor authentication
+func UserTotpDisable(c *gin.Context) {
+	var request requests.TotpDisableRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	err := userServices.UserTotpDisable(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
+
+// [POST] regenerate the recovery codes
+func UserRegenerateRecoveryCodes(c *gin.Context) {
+	var request requests.RegenerateRecoveryCodesRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	recoveryCodes, err := userServices.UserRegenerateRecoveryCodes(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, recoveryCodes)
+}
+
+// [POST] begin passkey registration
+func UserPasskeyRegisterBegin(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	options, err := userServices.UserPasskeyRegisterBegin(userID)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, options)
+}
+
+// [POST] finish passkey registration
+func UserPasskeyRegisterFinish(c *gin.Context) {
+	var request requests.PasskeyRegisterFinishRequest
+
+	if err := validation.BindAndValidate(c, &request); err != nil {
+		return
+	}
+
+	userID := c.GetString("userID")
+
+	credential, err := userServices.UserPasskeyRegisterFinish(userID, &request)
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, credential)
+}
+
+// [GET] get passkeys
+func GetUserPasskeys(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	credentials, err := userServices.GetUserPasskeys(userID)
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, credentials)
+}
+
+// [DELETE] delete passkey
+func DeleteUserPasskey(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	err := userServices.DeleteUserPasskey(userID, c.Param("id"))
+	if err != nil {
+		response.BadRequestWithMessage(c, "invalid passkey id")
+		return
+	}
+
+	response.Success(c)
+}
+
+// [GET] get the linked oauth accounts
+func GetUserIdentities(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	identities, err := userServices.GetUserIdentities(userID)
+	if err != nil {
+		response.InternalServerError(c)
+		return
+	}
+
+	response.SuccessWithData(c, identities)
+}
+
+// [POST] begin linking an oauth account, return the authorization url of the provider
+func UserLinkIdentityBegin(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	authURL, err := userServices.UserLinkIdentityBegin(userID, c.Param("provider"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.SuccessWithData(c, gin.H{"authUrl": authURL})
+}
+
+// [DELETE] unlink an oauth account
+func UserUnlinkIdentity(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	err := userServices.UserUnlinkIdentity(userID, c.Param("provider"))
+	if err != nil {
+		response.BadRequestWithMessage(c, err.Error())
+		return
+	}
+
+	response.Success(c)
+}
@@ -103,3 +103,8 @@ func RedisIncr(key string) (int64, error) {
 	}
 	return count, nil
 }
+
+// set the key only if it does not exist, return false if it exists
+func RedisSetNX(key string, value string, expiry int, unit datetime.TIME_UNIT) (bool, error) {
+	return RedisClient.SetNX(GetRedisContext(), key, value, time.Duration(expiry)*time.Duration(unit)).Result()
+}
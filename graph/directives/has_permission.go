@@ -0,0 +1,28 @@
+package directives
+
+import (
+	"context"
+	"errors"
+
+	"gin-auth-mongo/middlewares"
+	"gin-auth-mongo/utils/rbac"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// @hasPermission(permissions: [...]), the graphql version of middlewares.RequirePermission
+func HasPermission(ctx context.Context, obj interface{}, next graphql.Resolver, permissions []string) (interface{}, error) {
+	claims, err := middlewares.GetClaimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	granted, _ := claims["permissions"].([]string)
+	for _, permission := range permissions {
+		if !rbac.HasPermission(granted, permission) {
+			return nil, errors.New("Permission denied")
+		}
+	}
+
+	return next(ctx)
+}
@@ -9,8 +9,24 @@ import (
 )
 
 type AccessToken struct {
-	AccessToken       string `json:"accessToken"`
-	AccessTokenExpiry string `json:"accessTokenExpiry"`
+	AccessToken        string `json:"accessToken"`
+	AccessTokenExpiry  string `json:"accessTokenExpiry"`
+	RefreshToken       string `json:"refreshToken"`
+	RefreshTokenExpiry string `json:"refreshTokenExpiry"`
+}
+
+type AdminUserDetail struct {
+	User           *models.User            `json:"user"`
+	Sessions       []*Session              `json:"sessions"`
+	SecurityEvents []*models.SecurityEvent `json:"securityEvents"`
+}
+
+type AdminUserPage struct {
+	Items      []*models.User `json:"items"`
+	Total      int            `json:"total"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"pageSize"`
+	TotalPages int            `json:"totalPages"`
 }
 
 type HelloWorld struct {
@@ -18,8 +34,16 @@ type HelloWorld struct {
 }
 
 type LoginResponse struct {
-	User  *models.User `json:"user"`
-	Token *Token       `json:"token"`
+	User         *models.User  `json:"user,omitempty"`
+	Token        *Token        `json:"token,omitempty"`
+	MfaRequired  bool          `json:"mfaRequired"`
+	MfaChallenge *MfaChallenge `json:"mfaChallenge,omitempty"`
+}
+
+type MfaChallenge struct {
+	MfaToken       string   `json:"mfaToken"`
+	MfaTokenExpiry string   `json:"mfaTokenExpiry"`
+	Methods        []string `json:"methods"`
 }
 
 type Mutation struct {
@@ -37,6 +61,27 @@ type Token struct {
 	Device             string `json:"device"`
 }
 
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+type Session struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt"`
+	ExpiredAt  string `json:"expiredAt"`
+	Current    bool   `json:"current"`
+}
+
+type TotpSetup struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+	ExpiredAt  string `json:"expiredAt"`
+}
+
 type UploadAvatarRequest struct {
 	Avatar graphql.Upload `json:"avatar"`
 }
@@ -0,0 +1,71 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.55
+
+import (
+	"context"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/middlewares"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	adminService "gin-auth-mongo/services/admin"
+)
+
+// AdminForceUserPasswordReset is the resolver for the adminForceUserPasswordReset field.
+func (r *mutationResolver) AdminForceUserPasswordReset(ctx context.Context, id string) (bool, error) {
+	err := adminService.ForceUserPasswordReset(id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AdminRevokeUserSessions is the resolver for the adminRevokeUserSessions field.
+func (r *mutationResolver) AdminRevokeUserSessions(ctx context.Context, id string) (bool, error) {
+	err := adminService.RevokeUserSessions(id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AdminUpdateUserStatus is the resolver for the adminUpdateUserStatus field.
+func (r *mutationResolver) AdminUpdateUserStatus(ctx context.Context, id string, input requests.AdminUpdateUserStatusRequest) (*models.User, error) {
+	adminID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return adminService.UpdateUserStatus(adminID, id, &input)
+}
+
+// AdminUpdateUserPremium is the resolver for the adminUpdateUserPremium field.
+func (r *mutationResolver) AdminUpdateUserPremium(ctx context.Context, id string, input requests.AdminUpdateUserPremiumRequest) (*models.User, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return adminService.UpdateUserPremium(id, &input)
+}
+
+// AdminUsers is the resolver for the adminUsers field.
+func (r *queryResolver) AdminUsers(ctx context.Context, input requests.AdminUserListRequest) (*model.AdminUserPage, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return adminService.GetUsers(&input)
+}
+
+// AdminUser is the resolver for the adminUser field.
+func (r *queryResolver) AdminUser(ctx context.Context, id string) (*model.AdminUserDetail, error) {
+	return adminService.GetUser(id)
+}
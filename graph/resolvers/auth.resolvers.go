@@ -6,10 +6,10 @@ package resolvers
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"gin-auth-mongo/graph"
 	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/middlewares"
 	"gin-auth-mongo/models/requests"
 	authService "gin-auth-mongo/services/auth"
 )
@@ -56,6 +56,20 @@ func (r *mutationResolver) UserEmailRegisterWithCode(ctx context.Context, reques
 	return true, nil
 }
 
+// UserEmailRegisterWithCodeResend is the resolver for the userEmailRegisterWithCodeResend field.
+func (r *mutationResolver) UserEmailRegisterWithCodeResend(ctx context.Context, request requests.EmailCodeResendRequest) (bool, error) {
+	if err := request.Validate(); err != nil {
+		return false, err
+	}
+
+	err := authService.UserEmailRegisterWithCodeResend(&request)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // UserEmailRegisterWithCodeVerify is the resolver for the userEmailRegisterWithCodeVerify field.
 func (r *mutationResolver) UserEmailRegisterWithCodeVerify(ctx context.Context, request requests.EmailRegisterCodeVerifyRequest) (bool, error) {
 	if err := request.Validate(); err != nil {
@@ -76,12 +90,13 @@ func (r *mutationResolver) UserEmailLoginWithPassword(ctx context.Context, reque
 		return nil, err
 	}
 
-	user, token, err := authService.UserEmailLoginWithPassword(&request)
+	loginResponse, err := authService.UserEmailLoginWithPassword(&request)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.LoginResponse{User: user, Token: token}, nil
+	recordLoginClient(ctx, loginResponse)
+	return loginResponse, nil
 }
 
 // UserUsernameLoginWithPassword is the resolver for the userUsernameLoginWithPassword field.
@@ -90,12 +105,129 @@ func (r *mutationResolver) UserUsernameLoginWithPassword(ctx context.Context, re
 		return nil, err
 	}
 
-	user, token, err := authService.UserUsernameLoginWithPassword(&request)
+	loginResponse, err := authService.UserUsernameLoginWithPassword(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	recordLoginClient(ctx, loginResponse)
+	return loginResponse, nil
+}
+
+// UserLoginTwoFactorVerify is the resolver for the userLoginTwoFactorVerify field.
+func (r *mutationResolver) UserLoginTwoFactorVerify(ctx context.Context, request requests.TwoFactorLoginVerifyRequest) (*model.LoginResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	loginResponse, err := authService.UserLoginTwoFactorVerify(&request)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.LoginResponse{User: user, Token: token}, nil
+	recordLoginClient(ctx, loginResponse)
+	return loginResponse, nil
+}
+
+// UserUnlockAccount is the resolver for the userUnlockAccount field.
+func (r *mutationResolver) UserUnlockAccount(ctx context.Context, request requests.UnlockAccountRequest) (bool, error) {
+	if err := request.Validate(); err != nil {
+		return false, err
+	}
+
+	err := authService.UnlockAccount(&request)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserEmailLoginWithLink is the resolver for the userEmailLoginWithLink field.
+func (r *mutationResolver) UserEmailLoginWithLink(ctx context.Context, request requests.EmailLoginLinkRequest) (bool, error) {
+	if err := request.Validate(); err != nil {
+		return false, err
+	}
+
+	err := authService.UserEmailLoginWithLink(&request)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserEmailLoginWithLinkVerify is the resolver for the userEmailLoginWithLinkVerify field.
+func (r *mutationResolver) UserEmailLoginWithLinkVerify(ctx context.Context, request requests.EmailLoginLinkVerifyRequest) (*model.LoginResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	loginResponse, err := authService.UserEmailLoginLinkVerify(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	recordLoginClient(ctx, loginResponse)
+	return loginResponse, nil
+}
+
+// UserEmailLoginWithCode is the resolver for the userEmailLoginWithCode field.
+func (r *mutationResolver) UserEmailLoginWithCode(ctx context.Context, request requests.EmailLoginCodeRequest) (bool, error) {
+	if err := request.Validate(); err != nil {
+		return false, err
+	}
+
+	err := authService.UserEmailLoginWithCode(&request)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserEmailLoginWithCodeVerify is the resolver for the userEmailLoginWithCodeVerify field.
+func (r *mutationResolver) UserEmailLoginWithCodeVerify(ctx context.Context, request requests.EmailLoginCodeVerifyRequest) (*model.LoginResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	loginResponse, err := authService.UserEmailLoginCodeVerify(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	recordLoginClient(ctx, loginResponse)
+	return loginResponse, nil
+}
+
+// UserPhoneLoginWithCode is the resolver for the userPhoneLoginWithCode field.
+func (r *mutationResolver) UserPhoneLoginWithCode(ctx context.Context, request requests.PhoneLoginCodeRequest) (bool, error) {
+	if err := request.Validate(); err != nil {
+		return false, err
+	}
+
+	err := authService.UserPhoneLoginWithCode(&request)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserPhoneLoginWithCodeVerify is the resolver for the userPhoneLoginWithCodeVerify field.
+func (r *mutationResolver) UserPhoneLoginWithCodeVerify(ctx context.Context, request requests.PhoneLoginCodeVerifyRequest) (*model.LoginResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	loginResponse, err := authService.UserPhoneLoginCodeVerify(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	recordLoginClient(ctx, loginResponse)
+	return loginResponse, nil
 }
 
 // UserEmailResetPasswordWithLink is the resolver for the userEmailResetPasswordWithLink field.
@@ -140,23 +272,39 @@ func (r *mutationResolver) UserEmailResetPasswordWithCode(ctx context.Context, r
 	return true, nil
 }
 
+// UserEmailResetPasswordWithCodeResend is the resolver for the userEmailResetPasswordWithCodeResend field.
+func (r *mutationResolver) UserEmailResetPasswordWithCodeResend(ctx context.Context, request requests.EmailCodeResendRequest) (bool, error) {
+	if err := request.Validate(); err != nil {
+		return false, err
+	}
+
+	err := authService.UserEmailResetPasswordWithCodeResend(&request)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // UserEmailResetPasswordWithCodeVerify is the resolver for the userEmailResetPasswordWithCodeVerify field.
 func (r *mutationResolver) UserEmailResetPasswordWithCodeVerify(ctx context.Context, request requests.EmailPasswordResetCodeVerifyRequest) (bool, error) {
 	panic(fmt.Errorf("not implemented: UserEmailResetPasswordWithCodeVerify - userEmailResetPasswordWithCodeVerify"))
 }
 
 // RefreshToken is the resolver for the refreshToken field.
-func (r *queryResolver) RefreshToken(ctx context.Context) (*model.AccessToken, error) {
-	refreshToken, ok := ctx.Value("token").(string)
-	if !ok {
-		return nil, errors.New("failed to cast context to string")
+func (r *queryResolver) RefreshToken(ctx context.Context, request requests.RefreshTokenRequest) (*model.AccessToken, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
 	}
 
-	accessToken, err := authService.RefreshToken(refreshToken)
+	accessToken, err := authService.RefreshToken(&request)
 	if err != nil {
 		return nil, err
 	}
 
+	ip, userAgent := middlewares.GetClientFromContext(ctx)
+	authService.RecordSessionClient(accessToken.RefreshToken, ip, userAgent)
+
 	return accessToken, nil
 }
 
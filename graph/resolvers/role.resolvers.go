@@ -0,0 +1,69 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.55
+
+import (
+	"context"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	roleService "gin-auth-mongo/services/role"
+)
+
+// CreateRole is the resolver for the createRole field.
+func (r *mutationResolver) CreateRole(ctx context.Context, input requests.CreateRoleRequest) (*models.Role, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return roleService.CreateRole(&input)
+}
+
+// UpdateRole is the resolver for the updateRole field.
+func (r *mutationResolver) UpdateRole(ctx context.Context, input requests.UpdateRoleRequest) (*models.Role, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return roleService.UpdateRole(&input)
+}
+
+// DeleteRole is the resolver for the deleteRole field.
+func (r *mutationResolver) DeleteRole(ctx context.Context, name string) (bool, error) {
+	err := roleService.DeleteRole(name)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetUserRoles is the resolver for the setUserRoles field.
+func (r *mutationResolver) SetUserRoles(ctx context.Context, input requests.SetUserRolesRequest) (bool, error) {
+	if err := input.Validate(); err != nil {
+		return false, err
+	}
+
+	err := roleService.SetUserRoles(&input)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Roles is the resolver for the roles field.
+func (r *queryResolver) Roles(ctx context.Context) ([]*models.Role, error) {
+	roles, err := roleService.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.Role, 0, len(roles))
+	for i := range roles {
+		result = append(result, &roles[i])
+	}
+
+	return result, nil
+}
@@ -0,0 +1,18 @@
+package resolvers
+
+import (
+	"context"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/middlewares"
+	authService "gin-auth-mongo/services/auth"
+)
+
+// record the client of the new session, the client is added to the context by the graphql middleware
+// no token is issued while the login is waiting for the second factor
+func recordLoginClient(ctx context.Context, loginResponse *model.LoginResponse) {
+	if loginResponse.Token == nil {
+		return
+	}
+	ip, userAgent := middlewares.GetClientFromContext(ctx)
+	authService.RecordSessionClient(loginResponse.Token.RefreshToken, ip, userAgent)
+}
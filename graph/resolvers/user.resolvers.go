@@ -8,8 +8,10 @@ import (
 	"context"
 	"fmt"
 	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/middlewares"
 	"gin-auth-mongo/models"
 	"gin-auth-mongo/models/requests"
+	userServices "gin-auth-mongo/services/user"
 )
 
 // UserUpdateNickname is the resolver for the userUpdateNickname field.
@@ -29,15 +31,171 @@ func (r *mutationResolver) UserDeleteAccount(ctx context.Context) (bool, error)
 
 // UserLogoutCurrentDevice is the resolver for the userLogoutCurrentDevice field.
 func (r *mutationResolver) UserLogoutCurrentDevice(ctx context.Context, input requests.LogoutRequest) (bool, error) {
-	panic(fmt.Errorf("not implemented: UserLogoutCurrentDevice - userLogoutCurrentDevice"))
+	claims, err := middlewares.GetClaimsFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return false, err
+	}
+
+	userID, _ := claims["userID"].(string)
+	jti, _ := claims["jti"].(string)
+	familyID, _ := claims["fid"].(string)
+	expiredAtUnix, _ := claims["expiredAtUnix"].(int64)
+
+	err = userServices.UserLogoutCurrentDevice(userID, jti, familyID, expiredAtUnix, &input)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // UserLogoutAllDevice is the resolver for the userLogoutAllDevice field.
 func (r *mutationResolver) UserLogoutAllDevice(ctx context.Context) (bool, error) {
-	panic(fmt.Errorf("not implemented: UserLogoutAllDevice - userLogoutAllDevice"))
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = userServices.UserLogoutAllDevice(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserRevokeSession is the resolver for the userRevokeSession field.
+func (r *mutationResolver) UserRevokeSession(ctx context.Context, input requests.RevokeSessionRequest) (bool, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return false, err
+	}
+
+	err = userServices.RevokeUserSession(userID, input.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserUpdatePhone is the resolver for the userUpdatePhone field.
+func (r *mutationResolver) UserUpdatePhone(ctx context.Context, input requests.UpdatePhoneRequest) (bool, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return false, err
+	}
+
+	err = userServices.UserUpdatePhone(userID, &input)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserVerifyPhone is the resolver for the userVerifyPhone field.
+func (r *mutationResolver) UserVerifyPhone(ctx context.Context, input requests.PhoneVerifyRequest) (bool, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return false, err
+	}
+
+	err = userServices.UserVerifyPhone(userID, &input)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserTotpSetup is the resolver for the userTotpSetup field.
+func (r *mutationResolver) UserTotpSetup(ctx context.Context) (*model.TotpSetup, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return userServices.UserTotpSetup(userID)
+}
+
+// UserTotpConfirm is the resolver for the userTotpConfirm field.
+func (r *mutationResolver) UserTotpConfirm(ctx context.Context, input requests.TotpConfirmRequest) (*model.RecoveryCodes, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return userServices.UserTotpConfirm(userID, &input)
+}
+
+// UserTotpDisable is the resolver for the userTotpDisable field.
+func (r *mutationResolver) UserTotpDisable(ctx context.Context, input requests.TotpDisableRequest) (bool, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return false, err
+	}
+
+	err = userServices.UserTotpDisable(userID, &input)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UserRegenerateRecoveryCodes is the resolver for the userRegenerateRecoveryCodes field.
+func (r *mutationResolver) UserRegenerateRecoveryCodes(ctx context.Context, input requests.RegenerateRecoveryCodesRequest) (*model.RecoveryCodes, error) {
+	userID, err := middlewares.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return userServices.UserRegenerateRecoveryCodes(userID, &input)
 }
 
 // GetUser is the resolver for the getUser field.
 func (r *queryResolver) GetUser(ctx context.Context) (*models.User, error) {
 	panic(fmt.Errorf("not implemented: GetUser - getUser"))
 }
+
+// UserSessions is the resolver for the userSessions field.
+func (r *queryResolver) UserSessions(ctx context.Context) ([]*model.Session, error) {
+	claims, err := middlewares.GetClaimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims["userID"].(string)
+	familyID, _ := claims["fid"].(string)
+
+	return userServices.GetUserSessions(userID, familyID)
+}
@@ -1,20 +1,26 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"os"
 
 	"gin-auth-mongo/databases"
 	"gin-auth-mongo/graph"
+	"gin-auth-mongo/graph/directives"
 	"gin-auth-mongo/graph/resolvers"
 	"gin-auth-mongo/middlewares"
+	"gin-auth-mongo/repositories"
 
 	"gin-auth-mongo/routes"
 	"gin-auth-mongo/utils"
-	"gin-auth-mongo/utils/consts"
 	"gin-auth-mongo/utils/cron"
 	"gin-auth-mongo/utils/jwkmanager"
+	"gin-auth-mongo/utils/jwt"
 	"gin-auth-mongo/utils/mail"
+	"gin-auth-mongo/utils/oauth"
+	"gin-auth-mongo/utils/passkey"
+	"gin-auth-mongo/utils/sms"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
@@ -32,10 +38,19 @@ func main() {
 
 	utils.InitLoggerDir()
 
+	// the issuer of the openid connect provider, the tokens of the oidc clients carry no iss without it
+	if jwt.OIDCIssuer() == "" {
+		log.Fatal("Error: OIDC_ISSUER or BACKEND_URL is required")
+	}
+
 	// init databases
 	databases.InitRedis()
 	databases.InitMinio()
 	databases.InitMongoDB()
+	err = repositories.HashLegacyRefreshTokens()
+	if err != nil {
+		log.Fatalf("Error hashing refresh tokens: %v", err)
+	}
 	err = databases.RunMigrations()
 	if err != nil {
 		log.Fatalf("Error running migrations: %v", err)
@@ -45,15 +60,26 @@ func main() {
 	// init mail
 	mail.InitMail()
 
+	// init sms sender
+	sms.InitSMS()
+
+	// init passkey relying party
+	passkey.InitWebAuthn()
+
+	// init oauth providers
+	oauth.InitOAuthProviders()
+
 	// init jwk manager
-	err = jwkmanager.LoadSigningKeys(consts.PUBLIC_KEYS_FILE, consts.PRIVATE_KEYS_FILE)
+	jwkmanager.InitKeyStore()
+	err = jwkmanager.LoadSigningKeys()
 	if err != nil {
 		log.Printf("Error loading jwk keys: %v", err)
 		log.Println("Trying to create new jwk keys...")
 
-		// create new keys
-		err := jwkmanager.UpdateKeys()
-		if err != nil {
+		// create new keys, no token has been signed yet so they are active at once
+		// on a conflict another instance has just created the keys, they are loaded instead
+		err := jwkmanager.RotateKeys(true, "")
+		if err != nil && !errors.Is(err, jwkmanager.ErrKeySetConflict) {
 			log.Fatalf("Error creating new jwk keys: %v", err)
 			panic(err)
 		}
@@ -68,7 +94,10 @@ func main() {
 	// init routes
 	routes.SetupRoutes(r)
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &resolvers.Resolver{}}))
+	config := graph.Config{Resolvers: &resolvers.Resolver{}}
+	config.Directives.HasPermission = directives.HasPermission
+
+	srv := handler.NewDefaultServer(graph.NewExecutableSchema(config))
 
 	// support multipartform
 	srv.AddTransport(transport.MultipartForm{
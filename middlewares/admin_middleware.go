@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"os"
+
+	"gin-auth-mongo/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the admin api is protected by the ADMIN_API_TOKEN in the X-Admin-Token header
+// the admin api is disabled if ADMIN_API_TOKEN is not set
+func AdminTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminToken := os.Getenv("ADMIN_API_TOKEN")
+		requestToken := c.GetHeader("X-Admin-Token")
+
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(adminToken), []byte(requestToken)) != 1 {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -3,10 +3,12 @@ package middlewares
 import (
 	"context"
 	"errors"
+	"gin-auth-mongo/utils/account"
 	"gin-auth-mongo/utils/consts"
 	"gin-auth-mongo/utils/jwt"
 	"gin-auth-mongo/utils/response"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +30,11 @@ func GraphQLMiddleware() gin.HandlerFunc {
 		requestPath := c.Request.URL.Path
 		log.Println(requestPath)
 
+		// the login resolvers record the client of the new session
+		ctx := context.WithValue(c.Request.Context(), "clientIP", c.ClientIP())
+		ctx = context.WithValue(ctx, "userAgent", c.Request.UserAgent())
+		c.Request = c.Request.WithContext(ctx)
+
 		var token string
 		authHeader := c.Request.Header.Get("Authorization")
 		if authHeader != "" {
@@ -46,6 +53,12 @@ func GraphQLMiddleware() gin.HandlerFunc {
 				return
 			}
 
+			// the id tokens and the tokens issued to the oidc clients and the service accounts are not for the user api
+			if !jwt.IsUserAccessToken(claims) {
+				response.Unauthorized(c)
+				return
+			}
+
 			exp := int64(claims["exp"].(float64))
 			expiredAt := time.Unix(exp, 0).Format(consts.DATETIME_NANO_FORMAT)
 
@@ -55,8 +68,35 @@ func GraphQLMiddleware() gin.HandlerFunc {
 				return
 			}
 
+			// the user has been suspended, banned or disabled since the token was issued
+			userID, _ := claims["sub"].(string)
+			restriction, err := account.GetUserRestriction(userID)
+			if err != nil {
+				response.InternalServerError(c)
+				return
+			}
+			if restriction != "" {
+				response.Failure(c, http.StatusForbidden, "account is "+restriction)
+				return
+			}
+
+			// check if the token has been revoked, eg: logged out
+			revoked, err := jwt.IsAccessTokenRevoked(claims)
+			if err != nil {
+				response.InternalServerError(c)
+				return
+			}
+			if revoked {
+				response.Unauthorized(c)
+				return
+			}
+
 			jwtClaims := map[string]interface{}{
 				"userID":        claims["sub"],
+				"jti":           claims["jti"],
+				"fid":           claims["fid"],
+				"roles":         jwt.ClaimStrings(claims, "roles"),
+				"permissions":   jwt.ClaimStrings(claims, "permissions"),
 				"email":         claims["email"],
 				"expiredAt":     expiredAt,
 				"expiredAtUnix": exp,
@@ -81,3 +121,23 @@ func GetClaimsFromContext(ctx context.Context) (map[string]interface{}, error) {
 	}
 	return claims, nil
 }
+
+// get the ip and the user agent of the client from the context
+func GetClientFromContext(ctx context.Context) (string, string) {
+	ip, _ := ctx.Value("clientIP").(string)
+	userAgent, _ := ctx.Value("userAgent").(string)
+	return ip, userAgent
+}
+
+// get the user id from the claims in the context
+func GetUserIDFromContext(ctx context.Context) (string, error) {
+	claims, err := GetClaimsFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	userID, ok := claims["userID"].(string)
+	if !ok || userID == "" {
+		return "", errors.New("Unauthorized")
+	}
+	return userID, nil
+}
@@ -4,6 +4,7 @@ import (
 	// "log"
 	// "encoding/json"
 	// "log"
+	"net/http"
 	"time"
 
 	// "time"
@@ -12,13 +13,13 @@ import (
 	// "log"
 	"strings"
 
+	"gin-auth-mongo/utils/account"
 	"gin-auth-mongo/utils/consts"
 	"gin-auth-mongo/utils/jwkmanager"
 	"gin-auth-mongo/utils/jwt"
 	"gin-auth-mongo/utils/response"
 
 	"github.com/gin-gonic/gin"
-	"github.com/square/go-jose/v3"
 )
 
 func JWTAuthMiddleware() gin.HandlerFunc {
@@ -39,6 +40,23 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// the personal access tokens are opaque, what they can do is checked by the ScopeMiddleware
+		if jwt.IsPersonalAccessToken(token) {
+			personalAccessToken, err := jwt.ValidatePersonalAccessToken(token, c.ClientIP())
+			if err != nil {
+				response.Unauthorized(c)
+				c.Abort()
+				return
+			}
+
+			c.Set("userID", personalAccessToken.UserID.Hex())
+			c.Set("personalAccessTokenID", personalAccessToken.ID.Hex())
+			c.Set("scopes", personalAccessToken.Scopes)
+
+			c.Next()
+			return
+		}
+
 		parsedJWT, err := jwt.ParseToken(token)
 		if err != nil {
 			response.Unauthorized(c)
@@ -53,30 +71,24 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// get public jwks
-		publicJWKs, err := jwkmanager.GetPublicJWKs()
+		// get the public jwk of the kid, the alg in the header MUST be the alg of the key
+		publicJWK, err := jwkmanager.GetVerificationKey(parsedJWT.Headers[0].KeyID, parsedJWT.Headers[0].Algorithm)
 		if err != nil {
-			response.InternalServerError(c)
+			response.Unauthorized(c)
 			c.Abort()
 			return
 		}
 
-		// create jwks
-		JWKs := jose.JSONWebKeySet{
-			Keys: publicJWKs,
-		}
-		publicJWK := JWKs.Key(parsedJWT.Headers[0].KeyID)
-
-		// check if the public jwk is valid
-		if len(publicJWK) <= 0 {
+		// extract the claims
+		allClaims := make(map[string]interface{})
+		if err := parsedJWT.Claims(publicJWK.Key, &allClaims); err != nil {
 			response.Unauthorized(c)
 			c.Abort()
 			return
 		}
 
-		// extract the claims
-		allClaims := make(map[string]interface{})
-		if err := parsedJWT.Claims(publicJWK[0].Key, &allClaims); err != nil {
+		// the id tokens and the tokens issued to the oidc clients and the service accounts are not for the user api
+		if !jwt.IsUserAccessToken(allClaims) {
 			response.Unauthorized(c)
 			c.Abort()
 			return
@@ -91,7 +103,38 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// the user has been suspended, banned or disabled since the token was issued
+		userID, _ := allClaims["sub"].(string)
+		restriction, err := account.GetUserRestriction(userID)
+		if err != nil {
+			response.InternalServerError(c)
+			c.Abort()
+			return
+		}
+		if restriction != "" {
+			response.Failure(c, http.StatusForbidden, "account is "+restriction)
+			c.Abort()
+			return
+		}
+
+		// check if the token has been revoked, eg: logged out
+		revoked, err := jwt.IsAccessTokenRevoked(allClaims)
+		if err != nil {
+			response.InternalServerError(c)
+			c.Abort()
+			return
+		}
+		if revoked {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
 		c.Set("userID", allClaims["sub"])
+		c.Set("jti", allClaims["jti"])
+		c.Set("fid", allClaims["fid"])
+		c.Set("roles", jwt.ClaimStrings(allClaims, "roles"))
+		c.Set("permissions", jwt.ClaimStrings(allClaims, "permissions"))
 		c.Set("email", allClaims["email"])
 		c.Set("expiredAtUnix", exp)
 		// convert to time
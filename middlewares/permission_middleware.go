@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"gin-auth-mongo/utils/rbac"
+	"gin-auth-mongo/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the user MUST have all the permissions through the roles, see rbac.HasPermission
+// MUST be used after the JWTAuthMiddleware, the personal access tokens carry no permissions
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice("permissions")
+		for _, permission := range permissions {
+			if !rbac.HasPermission(granted, permission) {
+				response.PermissionDenied(c)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
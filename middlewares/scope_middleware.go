@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"gin-auth-mongo/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the request MUST carry all the scopes, eg: a route group of the internal api
+// MUST be used after the ServiceAccountAuthMiddleware or the JWTAuthMiddleware, the jwts of a login carry no scopes
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice("scopes")
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				response.Forbidden(c)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// a personal access token MUST have the scope of the resource, <resource>:read for the safe methods and <resource>:write for the others
+// the jwts of a login can do everything the user can
+// MUST be used after the JWTAuthMiddleware
+func ScopeMiddleware(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("personalAccessTokenID") == "" {
+			c.Next()
+			return
+		}
+
+		scope := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = resource + ":read"
+		}
+
+		if !slices.Contains(c.GetStringSlice("scopes"), scope) {
+			response.Forbidden(c)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// the personal access tokens are rejected, eg: managing the credentials, the sessions and the tokens of the user
+// MUST be used after the JWTAuthMiddleware
+func LoginOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("personalAccessTokenID") != "" {
+			response.Forbidden(c)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
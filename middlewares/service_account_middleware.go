@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"strings"
+	"time"
+
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// only the access tokens of the service accounts are accepted, eg: the internal api called by the backend jobs
+// use RequireScope to check what the service account can do
+func ServiceAccountAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := jwt.GetTokenFromHeader(c)
+		if err != nil {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
+		claims, err := jwt.ParseJWTClaims(token)
+		if err != nil {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
+		if claims["sub_type"] != consts.SUBJECT_TYPE_SERVICE_ACCOUNT || claims["iss"] != consts.JWT_ISSUER {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
+		exp, ok := claims["exp"].(float64)
+		if !ok || time.Now().Unix() > int64(exp) {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
+		// check if the token has been revoked, eg: the service account is disabled
+		revoked, err := jwt.IsAccessTokenRevoked(claims)
+		if err != nil {
+			response.InternalServerError(c)
+			c.Abort()
+			return
+		}
+		if revoked {
+			response.Unauthorized(c)
+			c.Abort()
+			return
+		}
+
+		scope, _ := claims["scope"].(string)
+
+		c.Set("serviceAccountID", claims["sub"])
+		c.Set("jti", claims["jti"])
+		c.Set("scopes", strings.Fields(scope))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// OAuthClient model for table `oauth_client`
+// an application which signs the users in with this server as the openid connect provider
+// the client id is the hex of ID
+type OAuthClient struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"clientId"`
+	OwnerID      primitive.ObjectID `bson:"owner_id" json:"ownerId"`
+	Name         string             `bson:"name" json:"name"`
+	SecretHash   string             `bson:"secret_hash" json:"-"` // empty for public clients
+	Public       bool               `bson:"public" json:"public"` // public clients MUST use pkce
+	RedirectURIs []string           `bson:"redirect_uris" json:"redirectUris"`
+	Scopes       []string           `bson:"scopes" json:"scopes"` // allowed scopes
+	CreatedAt    string             `bson:"created_at" json:"createdAt"`
+	UpdatedAt    string             `bson:"updated_at" json:"updatedAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// OAuthConsent model for table `oauth_consent`
+// the scopes the user has granted to the client
+type OAuthConsent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	ClientID  primitive.ObjectID `bson:"client_id" json:"clientId"`
+	Scopes    []string           `bson:"scopes" json:"scopes"`
+	CreatedAt string             `bson:"created_at" json:"createdAt"`
+	UpdatedAt string             `bson:"updated_at" json:"updatedAt"`
+}
@@ -0,0 +1,124 @@
+package requests
+
+var adminErrorMsg = map[string]string{
+	"Algorithm.oneof":           "algorithm must be one of EdDSA, ES256, RS256",
+	"Name.required":             "name is required",
+	"Name.max":                  "name must be at most 50 characters long",
+	"Scopes.required":           "scopes is required",
+	"Scopes.min":                "at least one scope is required",
+	"Name.alphanum":             "name must only contain letters and numbers",
+	"Description.max":           "description must be at most 200 characters long",
+	"Permissions.required":      "permissions is required",
+	"UserId.required":           "userId is required",
+	"Page.min":                  "page must be at least 1",
+	"PageSize.min":              "pageSize must be at least 1",
+	"PageSize.max":              "pageSize must be at most 100",
+	"Search.max":                "search must be at most 100 characters long",
+	"Status.required":           "status is required",
+	"Status.oneof":              "status must be one of active, disabled, suspended, banned",
+	"Reason.max":                "reason must be at most 200 characters long",
+	"Until.required_if":         "until is required when the user is suspended",
+	"Until.datetime":            "until must be in the format 2006-01-02T15:04:05.000",
+	"PremiumExpiredAt.datetime": "premiumExpiredAt must be in the format 2006-01-02T15:04:05.000",
+}
+
+// rotate the signing keys
+// immediate activates the new keys at once and retires the current ones, eg: when a key is compromised
+// algorithm is the algorithm of the new keys, the default algorithm is used if empty
+type RotateKeysRequest struct {
+	Immediate bool   `json:"immediate" form:"immediate"`
+	Algorithm string `json:"algorithm" form:"algorithm" validate:"omitempty,oneof=EdDSA ES256 RS256"`
+}
+
+func (r *RotateKeysRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// service accounts, the scopes are the scopes the account can request
+type ServiceAccountCreateRequest struct {
+	Name   string   `json:"name" form:"name" validate:"required,max=50"`
+	Scopes []string `json:"scopes" form:"scopes" validate:"required,min=1"`
+}
+
+func (r *ServiceAccountCreateRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// the fields which are not given are not changed
+type ServiceAccountUpdateRequest struct {
+	Name     string   `json:"name" form:"name" validate:"max=50"`
+	Scopes   []string `json:"scopes" form:"scopes" validate:"omitempty,min=1"`
+	Disabled *bool    `json:"disabled" form:"disabled"`
+}
+
+func (r *ServiceAccountUpdateRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// roles, the name is the key of the role and can not be changed
+type CreateRoleRequest struct {
+	Name        string   `json:"name" form:"name" validate:"required,alphanum,max=50"`
+	Description string   `json:"description" form:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" form:"permissions" validate:"required"`
+}
+
+func (r *CreateRoleRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+type UpdateRoleRequest struct {
+	Name        string   `json:"name" form:"name" validate:"required"`
+	Description string   `json:"description" form:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" form:"permissions" validate:"required"`
+}
+
+func (r *UpdateRoleRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// replace the roles of the user, an empty list removes all the roles
+type SetUserRolesRequest struct {
+	UserId string   `json:"userId" form:"userId" validate:"required"`
+	Roles  []string `json:"roles" form:"roles"`
+}
+
+func (r *SetUserRolesRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// list the users, the filters which are not given are not applied
+// search matches the email, the username and the nickname
+type AdminUserListRequest struct {
+	Page     int64  `json:"page" form:"page" validate:"omitempty,min=1"`
+	PageSize int64  `json:"pageSize" form:"pageSize" validate:"omitempty,min=1,max=100"`
+	Search   string `json:"search" form:"search" validate:"max=100"`
+	Status   string `json:"status" form:"status" validate:"omitempty,oneof=active disabled suspended banned"`
+	Premium  *bool  `json:"premium" form:"premium"`
+	Role     string `json:"role" form:"role"`
+}
+
+func (r *AdminUserListRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// change the status of the user, a user who is not active can not login
+// the reason is sent to the user, until is when the suspension ends and is ignored for the other statuses
+type AdminUpdateUserStatusRequest struct {
+	Status string `json:"status" form:"status" validate:"required,oneof=active disabled suspended banned"`
+	Reason string `json:"reason" form:"reason" validate:"max=200"`
+	Until  string `json:"until" form:"until" validate:"required_if=Status suspended,omitempty,datetime=2006-01-02T15:04:05.000"`
+}
+
+func (r *AdminUpdateUserStatusRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
+
+// premiumExpiredAt is empty if the premium does not expire
+type AdminUpdateUserPremiumRequest struct {
+	Premium          bool   `json:"premium" form:"premium"`
+	PremiumExpiredAt string `json:"premiumExpiredAt" form:"premiumExpiredAt" validate:"omitempty,datetime=2006-01-02T15:04:05.000"`
+}
+
+func (r *AdminUpdateUserPremiumRequest) Validate() error {
+	return FormatError(Validate.Struct(r), adminErrorMsg)
+}
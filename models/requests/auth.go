@@ -1,27 +1,35 @@
 package requests
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 )
 
 var authErrorMsg = map[string]string{
-	"Username.required": "Username is required",
-	"Username.min":      "Username must be at least 2 characters",
-	"Username.max":      "Username must be at most 32 characters",
-	"Username.regexp":   "Username must contain only letters, numbers, and underscores",
-	"Email.required":    "Email is required",
-	"Email.email":       "Invalid email format",
-	"Password.required": "Password is required",
-	"Password.min":      "Password must be at least 6 characters",
-	"FlowId.required":   "FlowId is required",
-	"Nickname.min":      "Nickname must be at least 2 characters",
-	"Nickname.max":      "Nickname must be at most 32 characters",
-	"Nickname.regexp":   "Nickname must contain only letters, numbers, and underscores",
-	"Code.regexp":       "Code format is invalid",
-	"Code.len":          "Code must be 6 characters",
-	"Code.required":     "Code is required",
-	"Device.max":        "Device must be at most 100 characters",
+	"Username.required":   "Username is required",
+	"Username.min":        "Username must be at least 2 characters",
+	"Username.max":        "Username must be at most 32 characters",
+	"Username.regexp":     "Username must contain only letters, numbers, and underscores",
+	"Email.required":      "Email is required",
+	"Email.email":         "Invalid email format",
+	"Password.required":   "Password is required",
+	"Password.min":        "Password must be at least 6 characters",
+	"FlowId.required":     "FlowId is required",
+	"Nickname.min":        "Nickname must be at least 2 characters",
+	"Nickname.max":        "Nickname must be at most 32 characters",
+	"Nickname.regexp":     "Nickname must contain only letters, numbers, and underscores",
+	"Code.regexp":         "Code format is invalid",
+	"Code.len":            "Code must be 6 characters",
+	"Code.required":       "Code is required",
+	"Device.max":          "Device must be at most 100 characters",
+	"MfaToken.required":   "MfaToken is required",
+	"SessionId.required":  "SessionId is required",
+	"Credential.required": "Credential is required",
+	"Code.min":            "Code must be at least 6 characters",
+	"Code.max":            "Code must be at most 11 characters",
+	"Phone.required":      "Phone is required",
+	"Phone.e164":          "Phone must be in E.164 format, eg: +8613800138000",
 }
 
 // register
@@ -47,6 +55,11 @@ type EmailRegisterCodeVerifyRequest struct {
 	Code  string `json:"code" form:"code" validate:"required,len=6"`
 }
 
+// send a new six-digit code for the pending registration or password reset
+type EmailCodeResendRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email"`
+}
+
 // login
 type EmailLoginWithPasswordRequest struct {
 	Email    string `json:"email" form:"email" validate:"required,email"`
@@ -60,6 +73,41 @@ type UsernameLoginWithPasswordRequest struct {
 	Device   string `json:"device" form:"device" validate:"max=100"`
 }
 
+// unlock the account locked by too many failed logins, the flow id is in the link sent by email
+type UnlockAccountRequest struct {
+	FlowId string `json:"flowId" form:"flowId" validate:"required"`
+}
+
+type EmailLoginLinkRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email"`
+}
+
+type EmailLoginLinkVerifyRequest struct {
+	FlowId string `json:"flowId" form:"flowId" validate:"required"`
+	Device string `json:"device" form:"device" validate:"max=100"`
+}
+
+type EmailLoginCodeRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email"`
+}
+
+type EmailLoginCodeVerifyRequest struct {
+	Email  string `json:"email" form:"email" validate:"required,email"`
+	Code   string `json:"code" form:"code" validate:"required,len=6"`
+	Device string `json:"device" form:"device" validate:"max=100"`
+}
+
+type TwoFactorLoginVerifyRequest struct {
+	MfaToken string `json:"mfaToken" form:"mfaToken" validate:"required"`
+	Code     string `json:"code" form:"code" validate:"required,min=6,max=11"` // totp code or recovery code
+}
+
+type PasskeyLoginFinishRequest struct {
+	SessionId  string          `json:"sessionId" form:"sessionId" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"` // response of navigator.credentials.get()
+	Device     string          `json:"device" form:"device" validate:"max=100"`
+}
+
 // reset password
 type EmailPasswordResetLinkRequest struct {
 	Email string `json:"email" form:"email" validate:"required,email"`
@@ -141,6 +189,10 @@ func (r *EmailRegisterCodeVerifyRequest) Validate() error {
 	return FormatError(Validate.Struct(r), authErrorMsg)
 }
 
+func (r *EmailCodeResendRequest) Validate() error {
+	return FormatError(Validate.Struct(r), authErrorMsg)
+}
+
 // login
 func (r *EmailLoginWithPasswordRequest) Validate() error {
 	err := FormatError(Validate.Struct(r), authErrorMsg)
@@ -159,7 +211,109 @@ func (r *UsernameLoginWithPasswordRequest) Validate() error {
 	return FormatError(Validate.Struct(r), authErrorMsg)
 }
 
+func (r *UnlockAccountRequest) Validate() error {
+	return FormatError(Validate.Struct(r), authErrorMsg)
+}
+
+func (r *EmailLoginLinkRequest) Validate() error {
+	return FormatError(Validate.Struct(r), authErrorMsg)
+}
+
+func (r *EmailLoginLinkVerifyRequest) Validate() error {
+	err := FormatError(Validate.Struct(r), authErrorMsg)
+	if err != nil {
+		return err
+	}
+
+	if r.Device == "" {
+		r.Device = "unknown"
+	}
+
+	return nil
+}
+
+func (r *EmailLoginCodeRequest) Validate() error {
+	return FormatError(Validate.Struct(r), authErrorMsg)
+}
+
+func (r *EmailLoginCodeVerifyRequest) Validate() error {
+	err := FormatError(Validate.Struct(r), authErrorMsg)
+	if err != nil {
+		return err
+	}
+
+	if r.Device == "" {
+		r.Device = "unknown"
+	}
+
+	return nil
+}
+
+func (r *TwoFactorLoginVerifyRequest) Validate() error {
+	return FormatError(Validate.Struct(r), authErrorMsg)
+}
+
+func (r *PasskeyLoginFinishRequest) Validate() error {
+	err := FormatError(Validate.Struct(r), authErrorMsg)
+	if err != nil {
+		return err
+	}
+
+	if r.Device == "" {
+		r.Device = "unknown"
+	}
+
+	return nil
+}
+
 // reset password
+// phone login
+type PhoneLoginCodeRequest struct {
+	Phone string `json:"phone" form:"phone" validate:"required,e164"`
+}
+
+type PhoneLoginCodeVerifyRequest struct {
+	Phone  string `json:"phone" form:"phone" validate:"required,e164"`
+	Code   string `json:"code" form:"code" validate:"required,len=6"`
+	Device string `json:"device" form:"device" validate:"max=100"`
+}
+
+func (r *PhoneLoginCodeRequest) Validate() error {
+	return FormatError(Validate.Struct(r), authErrorMsg)
+}
+
+func (r *PhoneLoginCodeVerifyRequest) Validate() error {
+	err := FormatError(Validate.Struct(r), authErrorMsg)
+	if err != nil {
+		return err
+	}
+
+	if r.Device == "" {
+		r.Device = "unknown"
+	}
+
+	return nil
+}
+
+// oauth login
+type OAuthLoginVerifyRequest struct {
+	FlowId string `json:"flowId" form:"flowId" validate:"required"`
+	Device string `json:"device" form:"device" validate:"max=100"`
+}
+
+func (r *OAuthLoginVerifyRequest) Validate() error {
+	err := FormatError(Validate.Struct(r), authErrorMsg)
+	if err != nil {
+		return err
+	}
+
+	if r.Device == "" {
+		r.Device = "unknown"
+	}
+
+	return nil
+}
+
 func (r *EmailPasswordResetLinkRequest) Validate() error {
 	return FormatError(Validate.Struct(r), authErrorMsg)
 }
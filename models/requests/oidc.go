@@ -0,0 +1,69 @@
+package requests
+
+var oidcErrorMsg = map[string]string{
+	"Name.required":         "name is required",
+	"Name.max":              "name must be at most 50 characters long",
+	"RedirectUris.required": "redirectUris is required",
+	"RedirectUris.min":      "at least one redirect uri is required",
+}
+
+// client registration
+type OIDCClientCreateRequest struct {
+	Name         string   `json:"name" form:"name" validate:"required,max=50"`
+	RedirectUris []string `json:"redirectUris" form:"redirectUris" validate:"required,min=1"`
+	Scopes       []string `json:"scopes" form:"scopes"`
+	Public       bool     `json:"public" form:"public"` // eg: spa and mobile apps which can not keep a secret
+}
+
+func (r *OIDCClientCreateRequest) Validate() error {
+	return FormatError(Validate.Struct(r), oidcErrorMsg)
+}
+
+// the query of the authorization endpoint, the errors are returned in the oauth2 format
+type OIDCAuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientId            string `form:"client_id"`
+	RedirectUri         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// the consent of the user on the consent screen
+type OIDCConsentRequest struct {
+	Approve bool `json:"approve" form:"approve"`
+}
+
+func (r *OIDCConsentRequest) Validate() error {
+	return FormatError(Validate.Struct(r), oidcErrorMsg)
+}
+
+// the form of the token endpoint, the errors are returned in the oauth2 format
+type OIDCTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectUri  string `form:"redirect_uri"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"` // client_credentials only
+}
+
+// the form of the introspection and the revocation endpoints, the errors are returned in the oauth2 format
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2.1
+// https://datatracker.ietf.org/doc/html/rfc7009#section-2.1
+type OIDCTokenIntrospectRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"` // access_token or refresh_token
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+type OIDCTokenRevokeRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"` // access_token or refresh_token
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
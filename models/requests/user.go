@@ -1,12 +1,29 @@
 package requests
 
+import "encoding/json"
+
 // import "mime/multipart
 
 var userErrorMsg = map[string]string{
-	"Nickname.required": "nickname is required",
-	"Nickname.min":      "nickname must be at least 1 characters long",
-	"Nickname.max":      "nickname must be at most 50 characters long",
-	"Avatar.required":   "avatar file is required",
+	"Nickname.required":   "nickname is required",
+	"Nickname.min":        "nickname must be at least 1 characters long",
+	"Nickname.max":        "nickname must be at most 50 characters long",
+	"Avatar.required":     "avatar file is required",
+	"Code.required":       "code is required",
+	"Code.len":            "code must be 6 characters",
+	"Code.min":            "code must be at least 6 characters",
+	"Code.max":            "code must be at most 11 characters",
+	"Password.required":   "password is required",
+	"Credential.required": "credential is required",
+	"Name.max":            "name must be at most 50 characters long",
+	"Phone.required":      "phone is required",
+	"Phone.e164":          "phone must be in E.164 format, eg: +8613800138000",
+	"ID.required":         "id is required",
+	"Name.required":       "name is required",
+	"Scopes.required":     "scopes is required",
+	"Scopes.min":          "at least one scope is required",
+	"ExpiresInDays.min":   "expiresInDays must be at least 1",
+	"ExpiresInDays.max":   "expiresInDays must be at most 365",
 }
 
 type UpdateNicknameRequest struct {
@@ -24,3 +41,77 @@ type UpdateAvatarRequest struct {
 func (r *UpdateAvatarRequest) Validate() error {
 	return FormatError(Validate.Struct(r), userErrorMsg)
 }
+
+// phone
+type UpdatePhoneRequest struct {
+	Phone string `json:"phone" form:"phone" validate:"required,e164"`
+}
+
+func (r *UpdatePhoneRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+type PhoneVerifyRequest struct {
+	Phone string `json:"phone" form:"phone" validate:"required,e164"`
+	Code  string `json:"code" form:"code" validate:"required,len=6"`
+}
+
+func (r *PhoneVerifyRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+// sessions
+type RevokeSessionRequest struct {
+	ID string `json:"id" form:"id" validate:"required"`
+}
+
+func (r *RevokeSessionRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+// personal access tokens, the token never expires if expiresInDays is 0
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" form:"name" validate:"required,max=50"`
+	Scopes        []string `json:"scopes" form:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" form:"expiresInDays" validate:"omitempty,min=1,max=365"`
+}
+
+func (r *CreatePersonalAccessTokenRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+// two factor authentication
+type TotpConfirmRequest struct {
+	Code string `json:"code" form:"code" validate:"required,len=6"`
+}
+
+func (r *TotpConfirmRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+type TotpDisableRequest struct {
+	Password string `json:"password" form:"password" validate:"required"`
+	Code     string `json:"code" form:"code" validate:"required,min=6,max=11"` // totp code or recovery code
+}
+
+func (r *TotpDisableRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+type RegenerateRecoveryCodesRequest struct {
+	Password string `json:"password" form:"password" validate:"required"`
+}
+
+func (r *RegenerateRecoveryCodesRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
+
+// passkey
+type PasskeyRegisterFinishRequest struct {
+	Name       string          `json:"name" form:"name" validate:"max=50"`
+	Credential json.RawMessage `json:"credential" validate:"required"` // response of navigator.credentials.create()
+}
+
+func (r *PasskeyRegisterFinishRequest) Validate() error {
+	return FormatError(Validate.Struct(r), userErrorMsg)
+}
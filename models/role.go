@@ -0,0 +1,14 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Role model for table `role`
+// a named set of permissions assigned to the users, the name is unique and can not be changed
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	Permissions []string           `bson:"permissions" json:"permissions"` // see consts.PERMISSIONS, "*" grants every permission
+	CreatedAt   string             `bson:"created_at" json:"createdAt"`
+	UpdatedAt   string             `bson:"updated_at" json:"updatedAt"`
+}
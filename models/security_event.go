@@ -0,0 +1,14 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SecurityEvent model for table `security_event`
+// the suspicious activities of a user, eg: a rotated refresh token is presented again
+type SecurityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	Type      string             `bson:"type" json:"type"`
+	Device    string             `bson:"device" json:"device"`
+	Detail    string             `bson:"detail" json:"detail"`
+	CreatedAt string             `bson:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ServiceAccount model for table `service_account`
+// a machine identity of the backend jobs, it is not a user and gets its tokens with the client credentials grant
+// the client id is the hex of ID
+type ServiceAccount struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"clientId"`
+	Name       string             `bson:"name" json:"name"`
+	SecretHash string             `bson:"secret_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"` // allowed scopes
+	Disabled   bool               `bson:"disabled" json:"disabled"`
+	CreatedAt  string             `bson:"created_at" json:"createdAt"`
+	UpdatedAt  string             `bson:"updated_at" json:"updatedAt"`
+	LastUsedAt string             `bson:"last_used_at" json:"lastUsedAt"` // when the last token was issued
+}
@@ -7,16 +7,25 @@ import (
 
 // User model for table `user`
 type User struct {
-	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username         string             `bson:"username" json:"username"`
-	Email            string             `bson:"email" json:"email"`
-	Password         string             `bson:"password" json:"-"`
-	Nickname         string             `bson:"nickname" json:"nickname"`
-	Avatar           string             `bson:"avatar" json:"avatar"`
-	CreatedAt        string             `bson:"created_at" json:"createdAt"`
-	UpdatedAt        string             `bson:"updated_at" json:"updatedAt"`
-	Country          string             `bson:"country" json:"country"`
-	Settings         bson.M             `bson:"settings" json:"settings"`
-	Premium          bool               `bson:"premium" json:"premium"`
-	PremiumExpiredAt string             `bson:"premium_expired_at" json:"premiumExpiredAt"`
+	ID                    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username              string             `bson:"username" json:"username"`
+	Email                 string             `bson:"email" json:"email"`
+	Phone                 string             `bson:"phone" json:"phone"` // verified phone number in E.164 format, empty if not bound
+	Password              string             `bson:"password" json:"-"`
+	Nickname              string             `bson:"nickname" json:"nickname"`
+	Avatar                string             `bson:"avatar" json:"avatar"`
+	CreatedAt             string             `bson:"created_at" json:"createdAt"`
+	UpdatedAt             string             `bson:"updated_at" json:"updatedAt"`
+	Country               string             `bson:"country" json:"country"`
+	Settings              bson.M             `bson:"settings" json:"settings"`
+	Premium               bool               `bson:"premium" json:"premium"`
+	PremiumExpiredAt      string             `bson:"premium_expired_at" json:"premiumExpiredAt"`
+	TwoFactorEnabled      bool               `bson:"two_factor_enabled" json:"twoFactorEnabled"`
+	TwoFactorSecret       string             `bson:"two_factor_secret" json:"-"`                           // encrypted totp secret
+	RecoveryCodes         []string           `bson:"recovery_codes" json:"-"`                              // hashed single-use recovery codes
+	Roles                 []string           `bson:"roles" json:"roles"`                                   // names of the roles, see Role
+	Status                string             `bson:"status" json:"status"`                                 // consts.USER_STATUS_*
+	StatusReason          string             `bson:"status_reason" json:"statusReason"`                    // why the user is not active, shown to the user
+	StatusUntil           string             `bson:"status_until" json:"statusUntil"`                      // when the suspension ends, empty for the other statuses
+	PasswordResetRequired bool               `bson:"password_reset_required" json:"passwordResetRequired"` // set by an admin, the password login is blocked until the password is reset
 }
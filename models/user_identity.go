@@ -0,0 +1,15 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// UserIdentity model for table `user_identity`
+// links an account of an external oauth provider to the user
+type UserIdentity struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	Provider   string             `bson:"provider" json:"provider"` // eg: google, github
+	Subject    string             `bson:"subject" json:"subject"`   // the user id at the provider
+	Email      string             `bson:"email" json:"email"`
+	CreatedAt  string             `bson:"created_at" json:"createdAt"`
+	LastUsedAt string             `bson:"last_used_at" json:"lastUsedAt"`
+}
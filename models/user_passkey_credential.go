@@ -0,0 +1,20 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// UserPasskeyCredential model for table `user_passkey_credential`
+type UserPasskeyCredential struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"userId"`
+	CredentialID    string             `bson:"credential_id" json:"credentialId"` // base64 raw url encoded
+	PublicKey       []byte             `bson:"public_key" json:"-"`
+	AttestationType string             `bson:"attestation_type" json:"attestationType"`
+	Transports      []string           `bson:"transports" json:"transports"`
+	AAGUID          []byte             `bson:"aaguid" json:"-"`
+	SignCount       int64              `bson:"sign_count" json:"-"`
+	BackupEligible  bool               `bson:"backup_eligible" json:"backupEligible"`
+	BackupState     bool               `bson:"backup_state" json:"backupState"`
+	Name            string             `bson:"name" json:"name"`
+	CreatedAt       string             `bson:"created_at" json:"createdAt"`
+	LastUsedAt      string             `bson:"last_used_at" json:"lastUsedAt"`
+}
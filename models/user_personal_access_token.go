@@ -0,0 +1,18 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// UserPersonalAccessToken model for table `user_personal_access_token`
+// a long-lived token for the scripts and the ci, it can only do what its scopes allow
+type UserPersonalAccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	Name       string             `bson:"name" json:"name"`
+	TokenHash  string             `bson:"token_hash" json:"-"`         // sha256 of the token, the raw token is only shown on creation
+	TokenHint  string             `bson:"token_hint" json:"tokenHint"` // the first characters of the token to tell the tokens apart
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	ExpiredAt  string             `bson:"expired_at" json:"expiredAt"` // empty if the token never expires
+	CreatedAt  string             `bson:"created_at" json:"createdAt"`
+	LastUsedAt string             `bson:"last_used_at" json:"lastUsedAt"` // empty if the token has never been used
+	LastUsedIP string             `bson:"last_used_ip" json:"lastUsedIp"`
+}
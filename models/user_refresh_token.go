@@ -3,10 +3,18 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 // RefreshToken model for table `user_refresh_token`
+// the refresh tokens of a login share a family, every refresh rotates the token within the family
+// the family is the session of the login, the client info and the created time are carried over on rotation
 type UserRefreshToken struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Token     string             `bson:"token" json:"token"`
-	ExpiredAt string             `bson:"expired_at" json:"expired_at"`
-	Device    string             `bson:"device" json:"device"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TokenHash  string             `bson:"token_hash" json:"-"` // sha256 of the token, the raw token is never stored
+	FamilyID   string             `bson:"family_id" json:"family_id"`
+	ExpiredAt  string             `bson:"expired_at" json:"expired_at"`
+	Device     string             `bson:"device" json:"device"`
+	IP         string             `bson:"ip" json:"ip"`
+	UserAgent  string             `bson:"user_agent" json:"user_agent"`
+	CreatedAt  string             `bson:"created_at" json:"created_at"`     // when the user logged in
+	LastUsedAt string             `bson:"last_used_at" json:"last_used_at"` // when the family was last refreshed
+	RotatedAt  string             `bson:"rotated_at" json:"rotated_at"`     // not empty once the token has been rotated, presenting it again is a reuse
 }
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var oauthClientTable = "oauth_client"
+
+func CreateOAuthClient(client *models.OAuthClient) error {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	client.ID = primitive.NewObjectID()
+	client.CreatedAt = now
+	client.UpdatedAt = now
+	return InsertOne(databases.GetMongoCollection(oauthClientTable), client)
+}
+
+func GetOAuthClientByID(clientID string) (*models.OAuthClient, error) {
+	idObject, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, err
+	}
+	var client models.OAuthClient
+	return FindOne(databases.GetMongoCollection(oauthClientTable), bson.M{"_id": idObject}, nil, &client)
+}
+
+func GetOAuthClientsByOwnerID(ownerID string) ([]models.OAuthClient, error) {
+	idObject, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var clients []models.OAuthClient
+	return FindManyWithoutPagination(databases.GetMongoCollection(oauthClientTable), bson.M{"owner_id": idObject}, nil, bson.D{{Key: "created_at", Value: 1}}, &clients)
+}
+
+func DeleteOAuthClientByIDAndOwnerID(clientID string, ownerID string) error {
+	idObject, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return err
+	}
+	ownerIDObject, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return err
+	}
+	return DeleteOne(databases.GetMongoCollection(oauthClientTable), bson.M{"_id": idObject, "owner_id": ownerIDObject})
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var oauthConsentTable = "oauth_consent"
+
+func GetOAuthConsent(userID string, clientID string) (*models.OAuthConsent, error) {
+	userIDObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	clientIDObject, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, err
+	}
+	var consent models.OAuthConsent
+	return FindOne(databases.GetMongoCollection(oauthConsentTable), bson.M{"user_id": userIDObject, "client_id": clientIDObject}, nil, &consent)
+}
+
+// create or replace the granted scopes of the user for the client
+func UpsertOAuthConsent(userID string, clientID string, scopes []string) error {
+	userIDObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	clientIDObject, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	_, err = databases.GetMongoCollection(oauthConsentTable).UpdateOne(context.TODO(),
+		bson.M{"user_id": userIDObject, "client_id": clientIDObject},
+		bson.M{
+			"$set":         bson.M{"scopes": scopes, "updated_at": now},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func DeleteOAuthConsentsByUserID(userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteMany(databases.GetMongoCollection(oauthConsentTable), bson.M{"user_id": idObject})
+}
+
+func DeleteOAuthConsentsByClientID(clientID string) error {
+	idObject, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return err
+	}
+	return DeleteMany(databases.GetMongoCollection(oauthConsentTable), bson.M{"client_id": idObject})
+}
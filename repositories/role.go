@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var roleTable = "role"
+
+func CreateRole(role *models.Role) error {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	role.CreatedAt = now
+	role.UpdatedAt = now
+	return InsertOne(databases.GetMongoCollection(roleTable), role)
+}
+
+func GetRoles() ([]models.Role, error) {
+	var roles []models.Role
+	return FindManyWithoutPagination(databases.GetMongoCollection(roleTable), nil, nil, bson.D{{Key: "name", Value: 1}}, &roles)
+}
+
+func GetRoleByName(name string) (*models.Role, error) {
+	var role models.Role
+	return FindOne(databases.GetMongoCollection(roleTable), bson.M{"name": name}, nil, &role)
+}
+
+func GetRolesByNames(names []string) ([]models.Role, error) {
+	var roles []models.Role
+	return FindManyWithoutPagination(databases.GetMongoCollection(roleTable), bson.M{"name": bson.M{"$in": names}}, nil, nil, &roles)
+}
+
+// update the description and the permissions of the role, return false if it does not exist
+func UpdateRoleByName(name string, description string, permissions []string) (bool, error) {
+	result, err := databases.GetMongoCollection(roleTable).UpdateOne(context.TODO(), bson.M{"name": name}, bson.M{"$set": bson.M{
+		"description": description,
+		"permissions": permissions,
+		"updated_at":  time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func DeleteRoleByName(name string) (bool, error) {
+	result, err := databases.GetMongoCollection(roleTable).DeleteOne(context.TODO(), bson.M{"name": name})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var securityEventTable = "security_event"
+
+func CreateSecurityEvent(userID primitive.ObjectID, eventType string, device string, detail string) error {
+	event := &models.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		Device:    device,
+		Detail:    detail,
+		CreatedAt: time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}
+	return InsertOne(databases.GetMongoCollection(securityEventTable), event)
+}
+
+func GetSecurityEventsByUserID(userID string) ([]models.SecurityEvent, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	var events []models.SecurityEvent
+	return FindManyWithoutPagination(databases.GetMongoCollection(securityEventTable), bson.M{"user_id": idObject}, nil, bson.D{{Key: "created_at", Value: -1}}, &events)
+}
+
+func DeleteSecurityEventsByUserID(userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteMany(databases.GetMongoCollection(securityEventTable), bson.M{"user_id": idObject})
+}
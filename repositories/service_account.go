@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var serviceAccountTable = "service_account"
+
+func CreateServiceAccount(account *models.ServiceAccount) error {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	account.ID = primitive.NewObjectID()
+	account.CreatedAt = now
+	account.UpdatedAt = now
+	return InsertOne(databases.GetMongoCollection(serviceAccountTable), account)
+}
+
+func GetServiceAccountByID(id string) (*models.ServiceAccount, error) {
+	idObject, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var account models.ServiceAccount
+	return FindOne(databases.GetMongoCollection(serviceAccountTable), bson.M{"_id": idObject}, nil, &account)
+}
+
+func GetServiceAccounts() ([]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	return FindManyWithoutPagination(databases.GetMongoCollection(serviceAccountTable), nil, nil, bson.D{{Key: "created_at", Value: 1}}, &accounts)
+}
+
+// update the fields of the service account, return false if it does not exist
+func UpdateServiceAccountByID(id string, fields bson.M) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	fields["updated_at"] = time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	result, err := databases.GetMongoCollection(serviceAccountTable).UpdateOne(context.TODO(), bson.M{"_id": idObject}, bson.M{"$set": fields})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func UpdateServiceAccountLastUsed(id primitive.ObjectID) error {
+	return UpdateOne(databases.GetMongoCollection(serviceAccountTable), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": time.Now().Format(consts.DATETIME_NANO_FORMAT)}})
+}
+
+func DeleteServiceAccountByID(id string) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := databases.GetMongoCollection(serviceAccountTable).DeleteOne(context.TODO(), bson.M{"_id": idObject})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
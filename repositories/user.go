@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"gin-auth-mongo/databases"
 	"gin-auth-mongo/models"
 	"gin-auth-mongo/utils/consts"
@@ -41,6 +42,11 @@ func GetUserByEmail(email string) (*models.User, error) {
 	return FindOne(databases.GetMongoCollection(userTable), bson.M{"email": email}, nil, &user)
 }
 
+func GetUserByPhone(phone string) (*models.User, error) {
+	var user models.User
+	return FindOne(databases.GetMongoCollection(userTable), bson.M{"phone": phone}, nil, &user)
+}
+
 func GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
 	return FindOne(databases.GetMongoCollection(userTable), bson.M{"username": username}, nil, &user)
@@ -57,10 +63,17 @@ func GetUserByEmailOrUsername(email, username string) (*models.User, error) {
 	return FindOne(databases.GetMongoCollection(userTable), filter, nil, &user)
 }
 
+// the users matching the filter, the newest first
+func GetUsers(filter bson.M, page, pageSize int64) (*PaginatedResult[models.User], error) {
+	var users []models.User
+	return FindMany(databases.GetMongoCollection(userTable), filter, bson.D{{Key: "_id", Value: -1}}, nil, page, pageSize, &users)
+}
+
 func CreateUser(email, username, password, nickname string) error {
 	user := models.User{
 		Username:         username,
 		Email:            email,
+		Phone:            "",
 		Password:         password,
 		Nickname:         nickname,
 		Avatar:           consts.DEFAULT_AVATAR,
@@ -68,6 +81,11 @@ func CreateUser(email, username, password, nickname string) error {
 		UpdatedAt:        time.Now().Format(consts.DATETIME_NANO_FORMAT),
 		Premium:          false,
 		PremiumExpiredAt: "",
+		TwoFactorEnabled: false,
+		TwoFactorSecret:  "",
+		RecoveryCodes:    []string{},
+		Roles:            []string{},
+		Status:           consts.USER_STATUS_ACTIVE,
 	}
 	return InsertOne(databases.GetMongoCollection(userTable), &user)
 }
@@ -88,7 +106,11 @@ func UpdateUserPasswordByID(userID string, password string) error {
 	if err != nil {
 		return err
 	}
-	return UpdateOne(databases.GetMongoCollection(userTable), bson.M{"_id": idObject}, bson.M{"$set": bson.M{"password": password}})
+	return UpdateOne(databases.GetMongoCollection(userTable), bson.M{"_id": idObject}, bson.M{"$set": bson.M{
+		"password":                password,
+		"password_reset_required": false,
+		"updated_at":              time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}})
 }
 
 func UpdateNicknameByID(userID string, nickname string) error {
@@ -107,6 +129,117 @@ func UpdateAvatarByID(userID string, avatar string) error {
 	return UpdateOne(databases.GetMongoCollection(userTable), bson.M{"_id": idObject}, bson.M{"$set": bson.M{"avatar": avatar}})
 }
 
+func UpdateUserPhoneByID(userID string, phone string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return UpdateOne(databases.GetMongoCollection(userTable), bson.M{"_id": idObject}, bson.M{"$set": bson.M{"phone": phone}})
+}
+
+func UpdateUserTwoFactorByID(userID string, enabled bool, encryptedSecret string, hashedRecoveryCodes []string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return UpdateOne(databases.GetMongoCollection(userTable), bson.M{"_id": idObject}, bson.M{"$set": bson.M{
+		"two_factor_enabled": enabled,
+		"two_factor_secret":  encryptedSecret,
+		"recovery_codes":     hashedRecoveryCodes,
+		"updated_at":         time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}})
+}
+
+func UpdateUserRecoveryCodesByID(userID string, hashedRecoveryCodes []string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return UpdateOne(databases.GetMongoCollection(userTable), bson.M{"_id": idObject}, bson.M{"$set": bson.M{"recovery_codes": hashedRecoveryCodes}})
+}
+
+// remove a used recovery code, return false if it was already consumed by another request
+func ConsumeUserRecoveryCodeByID(userID string, hashedRecoveryCode string) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+	result, err := databases.GetMongoCollection(userTable).UpdateOne(context.TODO(),
+		bson.M{"_id": idObject, "recovery_codes": hashedRecoveryCode},
+		bson.M{"$pull": bson.M{"recovery_codes": hashedRecoveryCode}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func UpdateUserRolesByID(userID string, roles []string) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+	result, err := databases.GetMongoCollection(userTable).UpdateOne(context.TODO(), bson.M{"_id": idObject}, bson.M{"$set": bson.M{
+		"roles":      roles,
+		"updated_at": time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// update the fields set by an admin, eg: the status and the premium, return false if the user is not found
+func UpdateUserByID(userID string, fields bson.M) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+	fields["updated_at"] = time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	result, err := databases.GetMongoCollection(userTable).UpdateOne(context.TODO(), bson.M{"_id": idObject}, bson.M{"$set": fields})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// set the users whose suspension has ended to active, return the number of the users
+// the datetime strings are compared as they sort in time order
+func LiftExpiredUserSuspensions() (int64, error) {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	result, err := databases.GetMongoCollection(userTable).UpdateMany(context.TODO(),
+		bson.M{"status": consts.USER_STATUS_SUSPENDED, "status_until": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{
+			"status":        consts.USER_STATUS_ACTIVE,
+			"status_reason": "",
+			"status_until":  "",
+			"updated_at":    now,
+		}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// the ids of the users who have the role
+func GetUserIDsByRole(role string) ([]primitive.ObjectID, error) {
+	var users []models.User
+	_, err := FindManyWithoutPagination(databases.GetMongoCollection(userTable), bson.M{"roles": role}, bson.M{"_id": 1}, nil, &users)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+func RemoveRoleFromUsers(role string) error {
+	return UpdateMany(databases.GetMongoCollection(userTable), bson.M{"roles": role}, bson.M{"$pull": bson.M{"roles": role}})
+}
+
 func DeleteUserByID(userID string) error {
 	idObject, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
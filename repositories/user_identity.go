@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var userIdentityTable = "user_identity"
+
+func CreateUserIdentity(identity *models.UserIdentity) error {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	identity.CreatedAt = now
+	identity.LastUsedAt = now
+	return InsertOne(databases.GetMongoCollection(userIdentityTable), identity)
+}
+
+func GetUserIdentityByProviderAndSubject(provider string, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	return FindOne(databases.GetMongoCollection(userIdentityTable), bson.M{"provider": provider, "subject": subject}, nil, &identity)
+}
+
+func GetUserIdentityByProviderAndUserID(provider string, userID string) (*models.UserIdentity, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	var identity models.UserIdentity
+	return FindOne(databases.GetMongoCollection(userIdentityTable), bson.M{"provider": provider, "user_id": idObject}, nil, &identity)
+}
+
+func GetUserIdentitiesByUserID(userID string) ([]models.UserIdentity, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	var identities []models.UserIdentity
+	return FindManyWithoutPagination(databases.GetMongoCollection(userIdentityTable), bson.M{"user_id": idObject}, nil, bson.D{{Key: "created_at", Value: 1}}, &identities)
+}
+
+func UpdateUserIdentityUsage(id primitive.ObjectID, email string) error {
+	return UpdateOne(databases.GetMongoCollection(userIdentityTable), bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"email":        email,
+		"last_used_at": time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}})
+}
+
+func DeleteUserIdentityByProviderAndUserID(provider string, userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteOne(databases.GetMongoCollection(userIdentityTable), bson.M{"provider": provider, "user_id": idObject})
+}
+
+func DeleteUserIdentitiesByUserID(userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteMany(databases.GetMongoCollection(userIdentityTable), bson.M{"user_id": idObject})
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var userPasskeyCredentialTable = "user_passkey_credential"
+
+func CreatePasskeyCredential(credential *models.UserPasskeyCredential) error {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	credential.CreatedAt = now
+	credential.LastUsedAt = now
+	return InsertOne(databases.GetMongoCollection(userPasskeyCredentialTable), credential)
+}
+
+func GetPasskeyCredentialsByUserID(userID string) ([]models.UserPasskeyCredential, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	var credentials []models.UserPasskeyCredential
+	return FindManyWithoutPagination(databases.GetMongoCollection(userPasskeyCredentialTable), bson.M{"user_id": idObject}, nil, bson.D{{Key: "created_at", Value: 1}}, &credentials)
+}
+
+func GetPasskeyCredentialByCredentialID(credentialID string) (*models.UserPasskeyCredential, error) {
+	var credential models.UserPasskeyCredential
+	return FindOne(databases.GetMongoCollection(userPasskeyCredentialTable), bson.M{"credential_id": credentialID}, nil, &credential)
+}
+
+func UpdatePasskeyCredentialUsage(credentialID string, signCount int64, backupState bool) error {
+	return UpdateOne(databases.GetMongoCollection(userPasskeyCredentialTable), bson.M{"credential_id": credentialID}, bson.M{"$set": bson.M{
+		"sign_count":   signCount,
+		"backup_state": backupState,
+		"last_used_at": time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}})
+}
+
+func DeletePasskeyCredentialByIDAndUserID(id string, userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	userIDObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteOne(databases.GetMongoCollection(userPasskeyCredentialTable), bson.M{"_id": idObject, "user_id": userIDObject})
+}
+
+func DeletePasskeyCredentialByUserID(userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteMany(databases.GetMongoCollection(userPasskeyCredentialTable), bson.M{"user_id": idObject})
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var userPersonalAccessTokenTable = "user_personal_access_token"
+
+func CreatePersonalAccessToken(token *models.UserPersonalAccessToken) error {
+	token.CreatedAt = time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	return InsertOne(databases.GetMongoCollection(userPersonalAccessTokenTable), token)
+}
+
+func GetPersonalAccessTokensByUserID(userID string) ([]models.UserPersonalAccessToken, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []models.UserPersonalAccessToken
+	return FindManyWithoutPagination(databases.GetMongoCollection(userPersonalAccessTokenTable), bson.M{"user_id": idObject}, nil, bson.D{{Key: "created_at", Value: -1}}, &tokens)
+}
+
+func CountPersonalAccessTokensByUserID(userID string) (int64, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, err
+	}
+	return databases.GetMongoCollection(userPersonalAccessTokenTable).CountDocuments(context.TODO(), bson.M{"user_id": idObject})
+}
+
+func GetPersonalAccessTokenByTokenHash(tokenHash string) (*models.UserPersonalAccessToken, error) {
+	var token models.UserPersonalAccessToken
+	return FindOne(databases.GetMongoCollection(userPersonalAccessTokenTable), bson.M{"token_hash": tokenHash}, nil, &token)
+}
+
+func UpdatePersonalAccessTokenUsage(id primitive.ObjectID, ip string) error {
+	return UpdateOne(databases.GetMongoCollection(userPersonalAccessTokenTable), bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"last_used_at": time.Now().Format(consts.DATETIME_NANO_FORMAT),
+		"last_used_ip": ip,
+	}})
+}
+
+func DeletePersonalAccessTokenByIDAndUserID(id string, userID string) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	userIDObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+	result, err := databases.GetMongoCollection(userPersonalAccessTokenTable).DeleteOne(context.TODO(), bson.M{"_id": idObject, "user_id": userIDObject})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func DeletePersonalAccessTokensByUserID(userID string) error {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return DeleteMany(databases.GetMongoCollection(userPersonalAccessTokenTable), bson.M{"user_id": idObject})
+}
@@ -1,9 +1,11 @@
 package repositories
 
 import (
+	"context"
 	"gin-auth-mongo/databases"
 	"gin-auth-mongo/models"
 	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,28 +15,105 @@ import (
 var userRefreshTokenTable = "user_refresh_token"
 
 type RefreshTokenRepository interface {
-	CreateRefreshToken(userID string, token string) error
-	GetRefreshTokenByToken(token string) (*models.UserRefreshToken, error)
+	CreateRefreshToken(userID string, tokenHash string, familyID string, expiredAt time.Time, device string) error
+	GetRefreshTokenByTokenHash(tokenHash string) (*models.UserRefreshToken, error)
 }
 
-func CreateRefreshToken(userID string, token string, expiredAt time.Time, device string) error {
+func CreateRefreshToken(userID string, tokenHash string, familyID string, expiredAt time.Time, device string) error {
 	userIDObject, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return err
 	}
 
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
 	refreshToken := &models.UserRefreshToken{
-		UserID:    userIDObject,
-		Token:     token,
-		ExpiredAt: expiredAt.Format(consts.DATETIME_NANO_FORMAT),
-		Device:    device,
+		UserID:     userIDObject,
+		TokenHash:  tokenHash,
+		FamilyID:   familyID,
+		ExpiredAt:  expiredAt.Format(consts.DATETIME_NANO_FORMAT),
+		Device:     device,
+		CreatedAt:  now,
+		LastUsedAt: now,
 	}
 	return InsertOne(databases.GetMongoCollection(userRefreshTokenTable), refreshToken)
 }
 
-func GetRefreshTokenByToken(token string) (*models.UserRefreshToken, error) {
+// create the next refresh token of the family, the session info of the previous token is carried over
+func CreateRotatedRefreshToken(previous *models.UserRefreshToken, tokenHash string) error {
+	refreshToken := &models.UserRefreshToken{
+		UserID:     previous.UserID,
+		TokenHash:  tokenHash,
+		FamilyID:   previous.FamilyID,
+		ExpiredAt:  previous.ExpiredAt,
+		Device:     previous.Device,
+		IP:         previous.IP,
+		UserAgent:  previous.UserAgent,
+		CreatedAt:  previous.CreatedAt,
+		LastUsedAt: time.Now().Format(consts.DATETIME_NANO_FORMAT),
+	}
+	return InsertOne(databases.GetMongoCollection(userRefreshTokenTable), refreshToken)
+}
+
+// record the client which is using the token
+func UpdateRefreshTokenClientByTokenHash(tokenHash string, ip string, userAgent string) error {
+	return UpdateOne(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"token_hash": tokenHash}, bson.M{"$set": bson.M{"ip": ip, "user_agent": userAgent}})
+}
+
+// mark the token as rotated, return false if it was already rotated by another request
+func RotateRefreshTokenByTokenHash(tokenHash string) (bool, error) {
+	result, err := databases.GetMongoCollection(userRefreshTokenTable).UpdateOne(context.TODO(),
+		bson.M{"token_hash": tokenHash, "rotated_at": ""},
+		bson.M{"$set": bson.M{"rotated_at": time.Now().Format(consts.DATETIME_NANO_FORMAT)}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// delete the expired refresh tokens and the tokens rotated before rotatedBefore, return the number of the tokens
+// the datetime strings are compared as they sort in time order
+func DeleteExpiredRefreshTokens(rotatedBefore time.Time) (int64, error) {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	result, err := databases.GetMongoCollection(userRefreshTokenTable).DeleteMany(context.TODO(), bson.M{"$or": []bson.M{
+		{"expired_at": bson.M{"$lte": now}},
+		{"rotated_at": bson.M{"$ne": "", "$lte": rotatedBefore.Format(consts.DATETIME_NANO_FORMAT)}},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func DeleteRefreshTokenByFamilyID(familyID string) error {
+	return DeleteMany(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"family_id": familyID})
+}
+
+// the current token of every family of the user, newest first, the expired ones are not filtered out
+func GetActiveRefreshTokensByUserID(userID string) ([]models.UserRefreshToken, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	var refreshTokens []models.UserRefreshToken
+	return FindManyWithoutPagination(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"user_id": idObject, "rotated_at": ""}, nil, bson.D{{Key: "last_used_at", Value: -1}}, &refreshTokens)
+}
+
+func DeleteRefreshTokenByUserIDAndFamilyID(userID string, familyID string) (bool, error) {
+	idObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+	result, err := databases.GetMongoCollection(userRefreshTokenTable).DeleteMany(context.TODO(), bson.M{"user_id": idObject, "family_id": familyID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func GetRefreshTokenByTokenHash(tokenHash string) (*models.UserRefreshToken, error) {
 	var refreshToken models.UserRefreshToken
-	return FindOne(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"token": token}, nil, &refreshToken)
+	return FindOne(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"token_hash": tokenHash}, nil, &refreshToken)
 }
 
 func DeleteRefreshTokenByUserIDAndDevice(userID string, device string) error {
@@ -53,6 +132,29 @@ func DeleteRefreshTokenByUserID(userID string) error {
 	return DeleteMany(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"user_id": idObject})
 }
 
-func DeleteRefreshTokenByToken(token string) error {
-	return DeleteOne(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"token": token})
+func DeleteRefreshTokenByTokenHash(tokenHash string) error {
+	return DeleteOne(databases.GetMongoCollection(userRefreshTokenTable), bson.M{"token_hash": tokenHash})
+}
+
+// hash the raw tokens stored before the refresh tokens were hashed, MUST run before the migrations
+// the raw token is removed by the migration 000015_update_user_refresh_token_hash, the users stay signed in
+func HashLegacyRefreshTokens() error {
+	collection := databases.GetMongoCollection(userRefreshTokenTable)
+
+	var legacyTokens []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Token string             `bson:"token"`
+	}
+	_, err := FindManyWithoutPagination(collection, bson.M{"token": bson.M{"$exists": true}, "token_hash": bson.M{"$exists": false}}, bson.M{"token": 1}, nil, &legacyTokens)
+	if err != nil {
+		return err
+	}
+
+	for _, legacyToken := range legacyTokens {
+		err := UpdateOne(collection, bson.M{"_id": legacyToken.ID}, bson.M{"$set": bson.M{"token_hash": crypto.HashToken(legacyToken.Token)}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	adminController "gin-auth-mongo/controllers/admin"
+	roleController "gin-auth-mongo/controllers/role"
+	"gin-auth-mongo/middlewares"
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v1/admin/*
+func AdminRoutes(r *gin.RouterGroup) {
+	admin := r.Group("/admin")
+	{
+		// the server operations are authenticated by the admin token
+		operations := admin.Group("")
+		operations.Use(middlewares.AdminTokenMiddleware())
+		operations.GET("/keys", adminController.GetSigningKeys)
+		operations.POST("/keys/rotate", adminController.RotateSigningKeys)
+
+		serviceAccounts := operations.Group("/service-accounts")
+		serviceAccounts.GET("", adminController.GetServiceAccounts)
+		serviceAccounts.POST("", adminController.CreateServiceAccount)
+		serviceAccounts.PUT("/:id", adminController.UpdateServiceAccount)
+		serviceAccounts.POST("/:id/secret", adminController.RotateServiceAccountSecret)
+		serviceAccounts.DELETE("/:id", adminController.DeleteServiceAccount)
+
+		// bootstrap the first admin, the roles are managed by the users with the permissions afterwards
+		operations.PUT("/users/roles", roleController.SetUserRoles)
+
+		// the users are managed by the users with the permissions, eg: the admin role
+		users := admin.Group("/users")
+		users.Use(middlewares.JWTAuthMiddleware(), middlewares.LoginOnlyMiddleware())
+		users.GET("", middlewares.RequirePermission(consts.PERMISSION_USERS_READ), adminController.GetUsers)
+		users.GET("/:id", middlewares.RequirePermission(consts.PERMISSION_USERS_READ), adminController.GetUser)
+		users.POST("/:id/password-reset", middlewares.RequirePermission(consts.PERMISSION_USERS_WRITE), adminController.ForceUserPasswordReset)
+		users.DELETE("/:id/sessions", middlewares.RequirePermission(consts.PERMISSION_USERS_WRITE), adminController.RevokeUserSessions)
+		users.PUT("/:id/status", middlewares.RequirePermission(consts.PERMISSION_USERS_WRITE), adminController.UpdateUserStatus)
+		users.PUT("/:id/premium", middlewares.RequirePermission(consts.PERMISSION_USERS_WRITE), adminController.UpdateUserPremium)
+	}
+}
@@ -14,14 +14,30 @@ func AuthRoutes(r *gin.RouterGroup) {
 		auth.POST("/register/email/link/verify", authController.UserEmailRegisterWithLinkVerify)
 		auth.GET("/register/email/link/check", authController.CheckUserEmailRegisterLinkExpired)
 		auth.POST("/register/email/code", authController.UserEmailRegisterWithCode)
+		auth.POST("/register/email/code/resend", authController.UserEmailRegisterWithCodeResend)
 		auth.POST("/register/email/code/verify", authController.UserEmailRegisterWithCodeVerify)
 
 		auth.POST("/login/email", authController.UserEmailLoginWithPassword)
 		auth.POST("/login/username", authController.UserUsernameLoginWithPassword)
+		auth.POST("/login/email/link", authController.UserEmailLoginWithLink)
+		auth.POST("/login/email/link/verify", authController.UserEmailLoginWithLinkVerify)
+		auth.POST("/login/email/code", authController.UserEmailLoginWithCode)
+		auth.POST("/login/email/code/verify", authController.UserEmailLoginWithCodeVerify)
+		auth.POST("/login/phone/code", authController.UserPhoneLoginWithCode)
+		auth.POST("/login/phone/code/verify", authController.UserPhoneLoginWithCodeVerify)
+		auth.POST("/login/2fa/verify", authController.UserLoginTwoFactorVerify)
+		auth.POST("/login/unlock", authController.UnlockAccount)
+		auth.POST("/login/passkey/begin", authController.UserPasskeyLoginBegin)
+		auth.POST("/login/passkey/finish", authController.UserPasskeyLoginFinish)
+
+		auth.GET("/oauth/:provider", authController.UserOAuthLoginBegin)
+		auth.GET("/oauth/:provider/callback", authController.UserOAuthCallback)
+		auth.POST("/oauth/login/verify", authController.UserOAuthLoginVerify)
 
 		auth.POST("/password-reset/email/link", authController.UserEmailResetPasswordWithLink)
 		auth.POST("/password-reset/email/link/verify", authController.UserEmailResetPasswordWithLinkVerify)
 		auth.POST("/password-reset/email/code", authController.UserEmailResetPasswordWithCode)
+		auth.POST("/password-reset/email/code/resend", authController.UserEmailResetPasswordWithCodeResend)
 		auth.POST("/password-reset/email/code/verify", authController.UserEmailResetPasswordWithCodeVerify)
 		auth.GET("/password-reset/email/link/check", authController.CheckUserEmailResetPasswordLinkExpired)
 
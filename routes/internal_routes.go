@@ -0,0 +1,20 @@
+package routes
+
+import (
+	adminController "gin-auth-mongo/controllers/admin"
+	"gin-auth-mongo/middlewares"
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v1/internal/*
+func InternalRoutes(r *gin.RouterGroup) {
+	// the internal api is called by the backend jobs with the access tokens of the service accounts
+	internal := r.Group("/internal")
+	internal.Use(middlewares.ServiceAccountAuthMiddleware())
+	{
+		users := internal.Group("/users")
+		users.GET("/:id", middlewares.RequireScope(consts.PERMISSION_USERS_READ), adminController.GetUser)
+	}
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	oidcController "gin-auth-mongo/controllers/oidc"
+	"gin-auth-mongo/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /.well-known/*
+// the discovery document MUST be served under the issuer
+func WellKnownRoutes(r *gin.Engine) {
+	wellKnown := r.Group("/.well-known")
+	{
+		wellKnown.GET("/openid-configuration", oidcController.Discovery)
+		wellKnown.GET("/oauth-authorization-server", oidcController.IssuerMetadata)
+		wellKnown.GET("/jwks.json", oidcController.JWKS)
+	}
+}
+
+// /api/v1/oidc/*
+func OIDCRoutes(r *gin.RouterGroup) {
+	oidc := r.Group("/oidc")
+	{
+		oidc.GET("/authorize", oidcController.Authorize)
+		oidc.POST("/token", oidcController.Token)
+		oidc.GET("/userinfo", oidcController.UserInfo)
+		oidc.POST("/userinfo", oidcController.UserInfo)
+
+		// the consent screen and the client registration need the user to be logged in
+		// the consent is given by the user, not by a personal access token
+		authorized := oidc.Group("")
+		authorized.Use(middlewares.JWTAuthMiddleware())
+		authorized.GET("/consent/:requestId", middlewares.LoginOnlyMiddleware(), oidcController.GetConsent)
+		authorized.POST("/consent/:requestId", middlewares.LoginOnlyMiddleware(), oidcController.SubmitConsent)
+
+		clients := authorized.Group("/clients")
+		clients.Use(middlewares.ScopeMiddleware("oidc"))
+		clients.GET("", oidcController.GetClients)
+		clients.POST("", oidcController.CreateClient)
+		clients.DELETE("/:id", oidcController.DeleteClient)
+	}
+}
+
+// /api/v1/oauth/*
+// authenticated by the client credentials, eg: the api gateway and the service accounts
+func OAuthRoutes(r *gin.RouterGroup) {
+	oauth := r.Group("/oauth")
+	{
+		oauth.POST("/token", oidcController.ClientCredentialsToken)
+		oauth.POST("/introspect", oidcController.Introspect)
+		oauth.POST("/revoke", oidcController.Revoke)
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	roleController "gin-auth-mongo/controllers/role"
+	"gin-auth-mongo/middlewares"
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v1/roles/*
+// the first admin is assigned with the admin token, see AdminRoutes
+func RoleRoutes(r *gin.RouterGroup) {
+	roles := r.Group("/roles")
+	roles.Use(middlewares.JWTAuthMiddleware(), middlewares.LoginOnlyMiddleware())
+	{
+		roles.GET("", middlewares.RequirePermission(consts.PERMISSION_ROLES_READ), roleController.GetRoles)
+		roles.POST("", middlewares.RequirePermission(consts.PERMISSION_ROLES_WRITE), roleController.CreateRole)
+		roles.PUT("", middlewares.RequirePermission(consts.PERMISSION_ROLES_WRITE), roleController.UpdateRole)
+		roles.DELETE("/:name", middlewares.RequirePermission(consts.PERMISSION_ROLES_WRITE), roleController.DeleteRole)
+		roles.PUT("/users", middlewares.RequirePermission(consts.PERMISSION_ROLES_WRITE), roleController.SetUserRoles)
+	}
+}
@@ -12,6 +12,8 @@ func SetupRoutes(r *gin.Engine) {
 	// flow limit middleware
 	r.Use(middlewares.CORSMiddleware(), middlewares.FlowLimitMiddleware())
 
+	WellKnownRoutes(r)
+
 	api := r.Group("/api")
 	{
 		logEnable := os.Getenv("LOG_ENABLE")
@@ -24,6 +26,11 @@ func SetupRoutes(r *gin.Engine) {
 			UserRoutes(v1)
 			AuthRoutes(v1)
 			FileRoutes(v1)
+			OIDCRoutes(v1)
+			OAuthRoutes(v1)
+			RoleRoutes(v1)
+			AdminRoutes(v1)
+			InternalRoutes(v1)
 		}
 
 	}
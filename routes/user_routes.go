@@ -10,7 +10,7 @@ import (
 // /api/v1/user/*
 func UserRoutes(r *gin.RouterGroup) {
 	user := r.Group("/user")
-	user.Use(middlewares.JWTAuthMiddleware())
+	user.Use(middlewares.JWTAuthMiddleware(), middlewares.ScopeMiddleware("user"))
 	{
 		user.GET("/", userController.GetUser)
 		user.PUT("/nickname", userController.UpdateNickname)
@@ -18,11 +18,44 @@ func UserRoutes(r *gin.RouterGroup) {
 		user.PUT("/avatar/upload", userController.UploadAvatar)
 		user.POST("/avatar/status", userController.GetAvatarStatus)
 
-		user.DELETE("/", userController.DeleteUserAccount)
+		// the personal access tokens can not change how the user signs in
+		login := user.Group("")
+		login.Use(middlewares.LoginOnlyMiddleware())
 
-		logout := user.Group("/logout")
+		login.PUT("/phone", userController.UserUpdatePhone)
+		login.POST("/phone/verify", userController.UserVerifyPhone)
+
+		login.DELETE("/", userController.DeleteUserAccount)
+
+		logout := login.Group("/logout")
 		logout.POST("", userController.UserLogoutCurrentDevice)
 		logout.POST("/all", userController.UserLogoutAllDevice)
 
+		sessions := login.Group("/sessions")
+		sessions.GET("", userController.GetUserSessions)
+		sessions.DELETE("/:id", userController.RevokeUserSession)
+
+		twoFactor := login.Group("/2fa")
+		twoFactor.POST("/totp/setup", userController.UserTotpSetup)
+		twoFactor.POST("/totp/confirm", userController.UserTotpConfirm)
+		twoFactor.POST("/totp/disable", userController.UserTotpDisable)
+		twoFactor.POST("/recovery-codes", userController.UserRegenerateRecoveryCodes)
+
+		passkeys := login.Group("/passkeys")
+		passkeys.GET("", userController.GetUserPasskeys)
+		passkeys.POST("/register/begin", userController.UserPasskeyRegisterBegin)
+		passkeys.POST("/register/finish", userController.UserPasskeyRegisterFinish)
+		passkeys.DELETE("/:id", userController.DeleteUserPasskey)
+
+		identities := login.Group("/identities")
+		identities.GET("", userController.GetUserIdentities)
+		identities.POST("/:provider", userController.UserLinkIdentityBegin)
+		identities.DELETE("/:provider", userController.UserUnlinkIdentity)
+
+		tokens := login.Group("/tokens")
+		tokens.GET("", userController.GetPersonalAccessTokens)
+		tokens.POST("", userController.CreatePersonalAccessToken)
+		tokens.DELETE("/:id", userController.DeletePersonalAccessToken)
+
 	}
 }
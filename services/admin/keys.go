@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"gin-auth-mongo/utils/jwkmanager"
+
+	"github.com/square/go-jose/v3"
+)
+
+func GetSigningKeys() []jwkmanager.KeyState {
+	return jwkmanager.GetKeyStates()
+}
+
+// rotate the signing keys, see jwkmanager.RotateKeys
+func RotateSigningKeys(immediate bool, algorithm string) ([]jwkmanager.KeyState, error) {
+	err := jwkmanager.RotateKeys(immediate, jose.SignatureAlgorithm(algorithm))
+	if err != nil {
+		return nil, err
+	}
+	return jwkmanager.GetKeyStates(), nil
+}
@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"errors"
+	"regexp"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CreatedServiceAccount is returned once on creation and on secret rotation, the secret can not be retrieved later
+type CreatedServiceAccount struct {
+	*models.ServiceAccount
+	ClientSecret string `json:"clientSecret"`
+}
+
+// https://datatracker.ietf.org/doc/html/rfc6749#section-3.3
+var scopeTokenRegex = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !scopeTokenRegex.MatchString(scope) {
+			return errors.New("invalid scope: " + scope)
+		}
+	}
+	return nil
+}
+
+func generateServiceAccountSecret() (string, string, error) {
+	secret, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	secretHash, err := crypto.HashPassword(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, secretHash, nil
+}
+
+func CreateServiceAccount(request *requests.ServiceAccountCreateRequest) (*CreatedServiceAccount, error) {
+
+	if err := validateScopes(request.Scopes); err != nil {
+		return nil, err
+	}
+
+	secret, secretHash, err := generateServiceAccountSecret()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	account := &models.ServiceAccount{
+		Name:       request.Name,
+		SecretHash: secretHash,
+		Scopes:     request.Scopes,
+	}
+
+	err = repositories.CreateServiceAccount(account)
+	if err != nil {
+		return nil, errors.New("create service account failed")
+	}
+
+	return &CreatedServiceAccount{ServiceAccount: account, ClientSecret: secret}, nil
+}
+
+func GetServiceAccounts() ([]models.ServiceAccount, error) {
+	accounts, err := repositories.GetServiceAccounts()
+	if err != nil {
+		return nil, errors.New("get service accounts failed")
+	}
+	if accounts == nil {
+		accounts = []models.ServiceAccount{}
+	}
+	return accounts, nil
+}
+
+// update the service account, the issued tokens are revoked when it is disabled or its scopes change
+func UpdateServiceAccount(id string, request *requests.ServiceAccountUpdateRequest) (*models.ServiceAccount, error) {
+
+	fields := bson.M{}
+	if request.Name != "" {
+		fields["name"] = request.Name
+	}
+	if request.Scopes != nil {
+		if err := validateScopes(request.Scopes); err != nil {
+			return nil, err
+		}
+		fields["scopes"] = request.Scopes
+	}
+	if request.Disabled != nil {
+		fields["disabled"] = *request.Disabled
+	}
+
+	found, err := repositories.UpdateServiceAccountByID(id, fields)
+	if err != nil || !found {
+		return nil, errors.New("service account not found")
+	}
+
+	if request.Scopes != nil || (request.Disabled != nil && *request.Disabled) {
+		if err := jwt.RevokeServiceAccountAccessTokens(id); err != nil {
+			return nil, errors.New("try again later")
+		}
+	}
+
+	account, err := repositories.GetServiceAccountByID(id)
+	if err != nil || account == nil {
+		return nil, errors.New("service account not found")
+	}
+
+	return account, nil
+}
+
+// rotate the secret, the tokens issued with the old secret are revoked
+func RotateServiceAccountSecret(id string) (*CreatedServiceAccount, error) {
+
+	secret, secretHash, err := generateServiceAccountSecret()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	found, err := repositories.UpdateServiceAccountByID(id, bson.M{"secret_hash": secretHash})
+	if err != nil || !found {
+		return nil, errors.New("service account not found")
+	}
+
+	if err := jwt.RevokeServiceAccountAccessTokens(id); err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	account, err := repositories.GetServiceAccountByID(id)
+	if err != nil || account == nil {
+		return nil, errors.New("service account not found")
+	}
+
+	return &CreatedServiceAccount{ServiceAccount: account, ClientSecret: secret}, nil
+}
+
+// delete the service account, the issued tokens are revoked
+func DeleteServiceAccount(id string) error {
+	deleted, err := repositories.DeleteServiceAccountByID(id)
+	if err != nil || !deleted {
+		return errors.New("service account not found")
+	}
+	return jwt.RevokeServiceAccountAccessTokens(id)
+}
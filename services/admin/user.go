@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"time"
+
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	authService "gin-auth-mongo/services/auth"
+	userService "gin-auth-mongo/services/user"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/mail"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// list the users, the newest first
+func GetUsers(request *requests.AdminUserListRequest) (*model.AdminUserPage, error) {
+
+	filter := bson.M{}
+	if request.Search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(request.Search), "$options": "i"}
+		filter["$or"] = []bson.M{
+			{"email": pattern},
+			{"username": pattern},
+			{"nickname": pattern},
+		}
+	}
+	if request.Status != "" {
+		filter["status"] = request.Status
+	}
+	if request.Premium != nil {
+		filter["premium"] = *request.Premium
+	}
+	if request.Role != "" {
+		filter["roles"] = request.Role
+	}
+
+	page := request.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := request.PageSize
+	if pageSize < 1 {
+		pageSize = consts.ADMIN_USER_PAGE_SIZE
+	}
+	pageSize = min(pageSize, consts.ADMIN_USER_MAX_PAGE_SIZE)
+
+	result, err := repositories.GetUsers(filter, page, pageSize)
+	if err != nil {
+		return nil, errors.New("get users failed")
+	}
+
+	users := make([]*models.User, 0, len(result.Items))
+	for i := range result.Items {
+		users = append(users, &result.Items[i])
+	}
+
+	return &model.AdminUserPage{
+		Items:      users,
+		Total:      int(result.Total),
+		Page:       int(result.Page),
+		PageSize:   int(result.PageSize),
+		TotalPages: int(result.TotalPages),
+	}, nil
+}
+
+// the user with its sessions and security events
+func GetUser(userID string) (*model.AdminUserDetail, error) {
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	sessions, err := userService.GetUserSessions(userID, "")
+	if err != nil {
+		return nil, errors.New("get sessions failed")
+	}
+
+	events, err := repositories.GetSecurityEventsByUserID(userID)
+	if err != nil {
+		return nil, errors.New("get security events failed")
+	}
+
+	securityEvents := make([]*models.SecurityEvent, 0, len(events))
+	for i := range events {
+		securityEvents = append(securityEvents, &events[i])
+	}
+
+	return &model.AdminUserDetail{User: user, Sessions: sessions, SecurityEvents: securityEvents}, nil
+}
+
+// block the password login until the user resets the password with the link sent by email
+// the sessions and the personal access tokens are revoked, the password may have been used to create them
+// the other login methods (eg: passkeys) still work
+func ForceUserPasswordReset(userID string) error {
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+
+	found, err := repositories.UpdateUserByID(userID, bson.M{"password_reset_required": true})
+	if err != nil || !found {
+		return errors.New("user not found")
+	}
+
+	if err := userService.UserLogoutAllDevice(userID); err != nil {
+		return errors.New("try again later")
+	}
+	if err := repositories.DeletePersonalAccessTokensByUserID(userID); err != nil {
+		return errors.New("try again later")
+	}
+
+	return authService.UserEmailResetPasswordWithLink(&requests.EmailPasswordResetLinkRequest{Email: user.Email})
+}
+
+// sign the user out of all the devices
+func RevokeUserSessions(userID string) error {
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+
+	if err := userService.UserLogoutAllDevice(userID); err != nil {
+		return errors.New("try again later")
+	}
+
+	return nil
+}
+
+// change the status of the user, a user who is not active is signed out of all the devices at once
+// the user is notified by email when suspended or banned
+// the admin can not restrict itself, there may be no other admin to lift it
+func UpdateUserStatus(adminID string, userID string, request *requests.AdminUpdateUserStatusRequest) (*models.User, error) {
+
+	if adminID == userID && request.Status != consts.USER_STATUS_ACTIVE {
+		return nil, errors.New("can not restrict your own account")
+	}
+
+	reason := request.Reason
+	until := ""
+	if request.Status == consts.USER_STATUS_ACTIVE {
+		reason = ""
+	}
+	if request.Status == consts.USER_STATUS_SUSPENDED {
+		untilTime, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, request.Until, time.Local)
+		if err != nil || !untilTime.After(time.Now()) {
+			return nil, errors.New("until must be in the future")
+		}
+		until = request.Until
+	}
+
+	found, err := repositories.UpdateUserByID(userID, bson.M{
+		"status":        request.Status,
+		"status_reason": reason,
+		"status_until":  until,
+	})
+	if err != nil || !found {
+		return nil, errors.New("user not found")
+	}
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if request.Status == consts.USER_STATUS_ACTIVE {
+		if err := account.LiftUserRestriction(userID); err != nil {
+			return nil, errors.New("try again later")
+		}
+		return user, nil
+	}
+
+	if err := account.RestrictUser(userID, request.Status, until); err != nil {
+		return nil, errors.New("try again later")
+	}
+	if err := userService.UserLogoutAllDevice(userID); err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	if request.Status == consts.USER_STATUS_SUSPENDED || request.Status == consts.USER_STATUS_BANNED {
+		go func() {
+			if err := mail.SendAccountStatusEmail(user.Email, user.Username, request.Status, reason, until).Error; err != nil {
+				log.Println("Error sending account status email:", err)
+			}
+		}()
+	}
+
+	return user, nil
+}
+
+// the premium is not in the access tokens, the change applies at once
+func UpdateUserPremium(userID string, request *requests.AdminUpdateUserPremiumRequest) (*models.User, error) {
+
+	premiumExpiredAt := request.PremiumExpiredAt
+	if !request.Premium {
+		premiumExpiredAt = ""
+	}
+
+	found, err := repositories.UpdateUserByID(userID, bson.M{"premium": request.Premium, "premium_expired_at": premiumExpiredAt})
+	if err != nil || !found {
+		return nil, errors.New("user not found")
+	}
+
+	return repositories.GetUserByID(userID)
+}
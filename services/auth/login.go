@@ -2,56 +2,231 @@ package auth
 
 import (
 	"errors"
+	"gin-auth-mongo/databases"
 	"gin-auth-mongo/graph/model"
 	"gin-auth-mongo/models"
 	"gin-auth-mongo/models/requests"
 	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/mail"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"gin-auth-mongo/utils/crypto"
 	"gin-auth-mongo/utils/jwt"
 )
 
-func UserEmailLoginWithPassword(request *requests.EmailLoginWithPasswordRequest) (*models.User, *model.Token, error) {
+// the same error whether the account is locked, delayed or does not exist
+var errTooManyFailedLogins = errors.New("too many failed attempts, please try again later")
 
-	user, err := repositories.GetUserByEmail(request.Email)
+// a password is verified against it when the user does not exist, so the response time does not tell if the user exists
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := crypto.HashPassword("dummy-password")
+	return hash
+})
+
+// the failed logins are counted per account, the attempts against an unknown email or username are counted per identifier
+func loginAttemptKey(user *models.User, identifier string) string {
+	if user != nil {
+		return user.ID.Hex()
+	}
+	return strings.ToLower(identifier)
+}
+
+// reserve an attempt before the password is verified, the parallel attempts are counted at once and can not pass the limits
+// reject the login while the account is locked, the delay after the last failure has not passed or the attempts are used up
+func reserveLoginAttempt(key string, user *models.User) (int64, error) {
+	attempts, err := databases.RedisIncr(consts.LOGIN_FAILED_ATTEMPTS + key)
+	if err != nil {
+		return 0, errors.New("try again later")
+	}
+	databases.RedisExpire(consts.LOGIN_FAILED_ATTEMPTS+key, consts.LOGIN_FAILED_WINDOW, datetime.MINUTES)
+
+	locked, err := databases.RedisExists(consts.LOGIN_LOCKOUT + key)
+	if err != nil {
+		return 0, errors.New("try again later")
+	}
+	if locked {
+		return 0, errTooManyFailedLogins
+	}
+
+	// the attempts rejected during the delay are counted as well
+	if attempts > consts.LOGIN_LOCKOUT_THRESHOLD {
+		lockLoginAttempts(key, user)
+		return 0, errTooManyFailedLogins
+	}
+
+	delayed, err := databases.RedisExists(consts.LOGIN_FAILED_DELAY + key)
+	if err != nil {
+		return 0, errors.New("try again later")
+	}
+	if delayed {
+		return 0, errTooManyFailedLogins
+	}
+
+	return attempts, nil
+}
+
+// the reserved attempt has failed, delay the next attempt and lock the account after too many failures
+func failLoginAttempt(key string, user *models.User, attempts int64) {
+	if attempts >= consts.LOGIN_LOCKOUT_THRESHOLD {
+		lockLoginAttempts(key, user)
+		return
+	}
+
+	if attempts >= consts.LOGIN_DELAY_THRESHOLD {
+		delay := min(int64(1)<<(attempts-consts.LOGIN_DELAY_THRESHOLD), consts.LOGIN_MAX_DELAY)
+		databases.RedisSet(consts.LOGIN_FAILED_DELAY+key, "1", int(delay), datetime.SECONDS)
+	}
+}
+
+// lock the account, the user is sent an unlock link once per lockout, nothing is sent for an unknown identifier
+func lockLoginAttempts(key string, user *models.User) {
+	locked, err := databases.RedisSetNX(consts.LOGIN_LOCKOUT+key, "1", consts.LOGIN_LOCKOUT_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		log.Printf("Error locking account: %v", err)
+		return
+	}
+	databases.RedisDel(consts.LOGIN_FAILED_ATTEMPTS + key)
+	if locked && user != nil {
+		go sendUnlockAccountLink(user)
+	}
+}
 
-	// user not found
-	if err != nil || user == nil {
-		return nil, nil, errors.New("incorrect email or password")
+func clearLoginAttempts(key string) {
+	databases.RedisDel(consts.LOGIN_FAILED_ATTEMPTS + key)
+	databases.RedisDel(consts.LOGIN_FAILED_DELAY + key)
+	databases.RedisDel(consts.LOGIN_LOCKOUT + key)
+}
+
+func sendUnlockAccountLink(user *models.User) {
+	flowID, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		log.Printf("Error generating unlock flow id: %v", err)
+		return
 	}
 
-	// check if the password is correct
-	match, err := crypto.VerifyPassword(request.Password, user.Password)
+	expiredAt := time.Now().Add(time.Duration(consts.LOGIN_LOCKOUT_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
+	link := os.Getenv("FRONTEND_URL") + consts.FRONTEND_UNLOCK_ACCOUNT_ROUTE + "?flow_id=" + flowID
+
+	if err := databases.RedisSet(consts.LOGIN_UNLOCK_FLOW_ID+flowID, user.ID.Hex(), consts.LOGIN_LOCKOUT_EXPIRY, datetime.MINUTES); err != nil {
+		log.Printf("Error storing unlock flow id: %v", err)
+		return
+	}
+
+	if err := mail.SendAccountLockedEmail(user.Email, user.Username, link, expiredAt).Error; err != nil {
+		log.Println("Error sending account locked email:", err)
+	}
+}
+
+// unlock the account with the link sent by email, the link can only be used once
+func UnlockAccount(request *requests.UnlockAccountRequest) error {
+	userID, err := databases.RedisGet(consts.LOGIN_UNLOCK_FLOW_ID + request.FlowId)
+	if err != nil || userID == "" {
+		return errors.New("invalid or expired link")
+	}
+
+	databases.RedisDel(consts.LOGIN_UNLOCK_FLOW_ID + request.FlowId)
+	clearLoginAttempts(userID)
+
+	return nil
+}
+
+// verify the password of the user found by the identifier against the reserved attempt, the failures are counted per account
+func verifyLoginPassword(user *models.User, key string, attempts int64, password string) bool {
+
+	if user == nil {
+		crypto.VerifyPassword(password, dummyPasswordHash())
+		failLoginAttempt(key, nil, attempts)
+		return false
+	}
+
+	match, err := crypto.VerifyPassword(password, user.Password)
 	if err != nil || !match {
-		return nil, nil, errors.New("incorrect email or password")
+		failLoginAttempt(key, user, attempts)
+		return false
 	}
 
-	token, err := jwt.HandleLogin(user, request.Device)
+	clearLoginAttempts(key)
+	return true
+}
+
+func UserEmailLoginWithPassword(request *requests.EmailLoginWithPasswordRequest) (*model.LoginResponse, error) {
+
+	user, err := repositories.GetUserByEmail(request.Email)
 	if err != nil {
-		return nil, nil, err
+		return nil, errors.New("try again later")
 	}
 
-	return user, token, nil
+	key := loginAttemptKey(user, "email:"+request.Email)
+	attempts, err := reserveLoginAttempt(key, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// the user is not found or the password is incorrect
+	if !verifyLoginPassword(user, key, attempts, request.Password) {
+		return nil, errors.New("incorrect email or password")
+	}
+
+	if user.PasswordResetRequired {
+		return nil, errors.New("password reset required, please check your email")
+	}
+
+	return completeLogin(user, request.Device)
 }
 
-func UserUsernameLoginWithPassword(request *requests.UsernameLoginWithPasswordRequest) (*models.User, *model.Token, error) {
+func UserUsernameLoginWithPassword(request *requests.UsernameLoginWithPasswordRequest) (*model.LoginResponse, error) {
+
 	user, err := repositories.GetUserByUsername(request.Username)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
 
-	// user not found
-	if err != nil || user == nil {
-		return nil, nil, errors.New("invalid username or password")
+	key := loginAttemptKey(user, "username:"+request.Username)
+	attempts, err := reserveLoginAttempt(key, user)
+	if err != nil {
+		return nil, err
 	}
 
-	// check if the password is correct
-	match, err := crypto.VerifyPassword(request.Password, user.Password)
-	if err != nil || !match {
-		return nil, nil, errors.New("invalid username or password")
+	// the user is not found or the password is incorrect
+	if !verifyLoginPassword(user, key, attempts, request.Password) {
+		return nil, errors.New("invalid username or password")
+	}
+
+	if user.PasswordResetRequired {
+		return nil, errors.New("password reset required, please check your email")
+	}
+
+	return completeLogin(user, request.Device)
+}
+
+// the password is verified, issue the tokens or a second factor challenge
+func completeLogin(user *models.User, device string) (*model.LoginResponse, error) {
+
+	// checked after the credentials are verified so it does not tell if the user exists
+	if err := account.CheckUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	// two factor is enabled, the tokens are issued after the code is verified
+	if user.TwoFactorEnabled {
+		challenge, err := createMfaChallenge(user, device)
+		if err != nil {
+			return nil, errors.New("try again later")
+		}
+		return &model.LoginResponse{MfaRequired: true, MfaChallenge: challenge}, nil
 	}
 
-	token, err := jwt.HandleLogin(user, request.Device)
+	token, err := jwt.HandleLogin(user, device)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return user, token, nil
+	return &model.LoginResponse{User: user, Token: token}, nil
 }
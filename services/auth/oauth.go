@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/oauth"
+
+	"golang.org/x/oauth2"
+)
+
+// oauthState is kept in redis between the redirect and the callback
+// userID is set when a logged-in user links the provider to the account
+type oauthState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	UserID   string `json:"userId,omitempty"`
+}
+
+// create the state, nonce and pkce verifier and return the authorization url of the provider
+func CreateOAuthAuthorizationURL(providerName string, userID string) (string, error) {
+
+	provider, err := oauth.GetProvider(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	stateID, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return "", errors.New("try again later")
+	}
+	nonce, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return "", errors.New("try again later")
+	}
+
+	state := oauthState{
+		Provider: provider.Name,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		UserID:   userID,
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", errors.New("try again later")
+	}
+
+	err = oauthStorage.SetState(stateID, string(data))
+	if err != nil {
+		return "", errors.New("try again later")
+	}
+
+	return provider.AuthCodeURL(stateID, state.Nonce, state.Verifier), nil
+}
+
+// get and delete the state, it can only be used once
+func popOAuthState(stateID string) (*oauthState, error) {
+	data, err := oauthStorage.PopState(stateID)
+	if err != nil || data == "" {
+		return nil, errors.New("invalid or expired oauth state")
+	}
+
+	var state oauthState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, errors.New("invalid or expired oauth state")
+	}
+	return &state, nil
+}
+
+func frontendURL(route string, query url.Values) string {
+	return os.Getenv("FRONTEND_URL") + route + "?" + query.Encode()
+}
+
+// handle the redirect from the provider and return the frontend url to redirect to
+// login: the frontend gets a one-time flow id and exchanges it for the tokens with UserOAuthLoginVerify
+// link: the frontend gets the linked provider or the error
+func UserOAuthCallback(providerName string, stateID string, code string, providerError string) string {
+
+	state, err := popOAuthState(stateID)
+	if err != nil || state.Provider != providerName {
+		return frontendURL(consts.FRONTEND_OAUTH_LOGIN_ROUTE, url.Values{"error": {"invalid or expired oauth state"}})
+	}
+
+	route := consts.FRONTEND_OAUTH_LOGIN_ROUTE
+	if state.UserID != "" {
+		route = consts.FRONTEND_OAUTH_LINK_ROUTE
+	}
+
+	if providerError != "" || code == "" {
+		return frontendURL(route, url.Values{"error": {"authorization was denied"}})
+	}
+
+	provider, err := oauth.GetProvider(providerName)
+	if err != nil {
+		return frontendURL(route, url.Values{"error": {err.Error()}})
+	}
+
+	externalUser, err := provider.Exchange(context.Background(), code, state.Nonce, state.Verifier)
+	if err != nil || externalUser.Subject == "" {
+		return frontendURL(route, url.Values{"error": {"failed to get the user from " + providerName}})
+	}
+
+	// link the provider to the logged-in user
+	if state.UserID != "" {
+		err = linkOAuthIdentity(state.UserID, providerName, externalUser)
+		if err != nil {
+			return frontendURL(route, url.Values{"error": {err.Error()}})
+		}
+		return frontendURL(route, url.Values{"linked": {providerName}})
+	}
+
+	user, err := resolveOAuthUser(providerName, externalUser)
+	if err != nil {
+		return frontendURL(route, url.Values{"error": {err.Error()}})
+	}
+
+	flowID, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return frontendURL(route, url.Values{"error": {"try again later"}})
+	}
+	err = oauthStorage.SetLoginFlow(flowID, user.ID.Hex())
+	if err != nil {
+		return frontendURL(route, url.Values{"error": {"try again later"}})
+	}
+
+	return frontendURL(route, url.Values{"flow_id": {flowID}})
+}
+
+// exchange the one-time flow id from the callback for the tokens
+func UserOAuthLoginVerify(request *requests.OAuthLoginVerifyRequest) (*model.LoginResponse, error) {
+
+	// the flow id can only be used once
+	userID, err := oauthStorage.PopLoginFlow(request.FlowId)
+	if err != nil || userID == "" {
+		return nil, errors.New("invalid or expired flow id")
+	}
+
+	user, err := oauthStorage.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.New("invalid or expired flow id")
+	}
+
+	return completeLogin(user, request.Device)
+}
+
+// find the user of the external account
+// an unknown account is linked to the user with the same verified email, or a new user is created
+func resolveOAuthUser(providerName string, externalUser *oauth.ExternalUser) (*models.User, error) {
+
+	identity, err := oauthStorage.GetIdentityByProviderAndSubject(providerName, externalUser.Subject)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	if identity != nil {
+		user, err := oauthStorage.GetUserByID(identity.UserID.Hex())
+		if err != nil || user == nil {
+			return nil, errors.New("user not found")
+		}
+		oauthStorage.UpdateIdentityUsage(identity.ID, externalUser.Email)
+		return user, nil
+	}
+
+	// the email must be verified by the provider, otherwise anyone could take over the account with the same email
+	if externalUser.Email == "" || !externalUser.EmailVerified {
+		return nil, errors.New("the email of the " + providerName + " account is not verified")
+	}
+
+	user, err := oauthStorage.GetUserByEmail(externalUser.Email)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	if user == nil {
+		user, err = createOAuthUser(externalUser.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = oauthStorage.CreateIdentity(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  externalUser.Subject,
+		Email:    externalUser.Email,
+	})
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	return user, nil
+}
+
+var invalidUsernameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// create a user for the external account
+// the password is random, the user can set one with the password reset flow
+func createOAuthUser(email string) (*models.User, error) {
+
+	suffix, err := GenerateVerificationCode()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	name := invalidUsernameChars.ReplaceAllString(strings.Split(email, "@")[0], "")
+	if len(name) < 2 {
+		name = "user"
+	}
+	if len(name) > 25 {
+		name = name[:25]
+	}
+	username := name + "_" + suffix
+
+	password, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+	hashedPassword, err := crypto.HashPassword(password)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	err = oauthStorage.CreateUser(email, username, hashedPassword, username)
+	if err != nil {
+		return nil, errors.New("create user failed")
+	}
+
+	user, err := oauthStorage.GetUserByEmail(email)
+	if err != nil || user == nil {
+		return nil, errors.New("create user failed")
+	}
+
+	return user, nil
+}
+
+// link the external account to the user, one account per provider
+func linkOAuthIdentity(userID string, providerName string, externalUser *oauth.ExternalUser) error {
+
+	user, err := oauthStorage.GetUserByID(userID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+
+	identity, err := oauthStorage.GetIdentityByProviderAndSubject(providerName, externalUser.Subject)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if identity != nil {
+		if identity.UserID == user.ID {
+			return errors.New("the " + providerName + " account is already linked")
+		}
+		return errors.New("the " + providerName + " account is linked to another user")
+	}
+
+	identity, err = oauthStorage.GetIdentityByProviderAndUserID(providerName, userID)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if identity != nil {
+		return errors.New("another " + providerName + " account is already linked, unlink it first")
+	}
+
+	err = oauthStorage.CreateIdentity(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  externalUser.Subject,
+		Email:    externalUser.Email,
+	})
+	if err != nil {
+		return errors.New("link " + providerName + " account failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// oauthStore keeps the states and the login flows of the oauth login and the users and their linked accounts
+// the tests replace it with an in-memory store, the decisions of the callback are tested without redis and mongodb
+type oauthStore interface {
+	SetState(stateID string, state string) error
+	PopState(stateID string) (string, error) // "" if the state does not exist, it can only be used once
+	SetLoginFlow(flowID string, userID string) error
+	PopLoginFlow(flowID string) (string, error) // "" if the flow does not exist, it can only be used once
+
+	GetUserByID(userID string) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	CreateUser(email string, username string, hashedPassword string, nickname string) error
+
+	GetIdentityByProviderAndSubject(provider string, subject string) (*models.UserIdentity, error)
+	GetIdentityByProviderAndUserID(provider string, userID string) (*models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	UpdateIdentityUsage(id primitive.ObjectID, email string) error
+}
+
+var oauthStorage oauthStore = repositoryOAuthStore{}
+
+// the states and the login flows in redis, the users and the linked accounts in mongodb
+type repositoryOAuthStore struct{}
+
+func (repositoryOAuthStore) SetState(stateID string, state string) error {
+	return databases.RedisSet(consts.OAUTH_STATE+stateID, state, consts.OAUTH_STATE_EXPIRY, datetime.MINUTES)
+}
+
+func (repositoryOAuthStore) PopState(stateID string) (string, error) {
+	return popRedisValue(consts.OAUTH_STATE + stateID)
+}
+
+func (repositoryOAuthStore) SetLoginFlow(flowID string, userID string) error {
+	return databases.RedisSet(consts.OAUTH_LOGIN_FLOW_ID+flowID, userID, consts.OAUTH_LOGIN_FLOW_EXPIRY, datetime.MINUTES)
+}
+
+func (repositoryOAuthStore) PopLoginFlow(flowID string) (string, error) {
+	return popRedisValue(consts.OAUTH_LOGIN_FLOW_ID + flowID)
+}
+
+func popRedisValue(key string) (string, error) {
+	value, err := databases.RedisGet(key)
+	if err != nil || value == "" {
+		return "", err
+	}
+	databases.RedisDel(key)
+	return value, nil
+}
+
+func (repositoryOAuthStore) GetUserByID(userID string) (*models.User, error) {
+	return repositories.GetUserByID(userID)
+}
+
+func (repositoryOAuthStore) GetUserByEmail(email string) (*models.User, error) {
+	return repositories.GetUserByEmail(email)
+}
+
+func (repositoryOAuthStore) CreateUser(email string, username string, hashedPassword string, nickname string) error {
+	return repositories.CreateUser(email, username, hashedPassword, nickname)
+}
+
+func (repositoryOAuthStore) GetIdentityByProviderAndSubject(provider string, subject string) (*models.UserIdentity, error) {
+	return repositories.GetUserIdentityByProviderAndSubject(provider, subject)
+}
+
+func (repositoryOAuthStore) GetIdentityByProviderAndUserID(provider string, userID string) (*models.UserIdentity, error) {
+	return repositories.GetUserIdentityByProviderAndUserID(provider, userID)
+}
+
+func (repositoryOAuthStore) CreateIdentity(identity *models.UserIdentity) error {
+	return repositories.CreateUserIdentity(identity)
+}
+
+func (repositoryOAuthStore) UpdateIdentityUsage(id primitive.ObjectID, email string) error {
+	return repositories.UpdateUserIdentityUsage(id, email)
+}
@@ -0,0 +1,399 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"testing"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/oauth"
+	"gin-auth-mongo/utils/oauth/oauthtest"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryOAuthStore keeps the states, the users and the linked accounts in memory
+type memoryOAuthStore struct {
+	mu         sync.Mutex
+	states     map[string]string
+	flows      map[string]string
+	users      []*models.User
+	identities []*models.UserIdentity
+}
+
+// replace the store of the oauth login, the previous store is restored when the test ends
+func setupMemoryStore(t *testing.T) *memoryOAuthStore {
+	t.Helper()
+
+	store := &memoryOAuthStore{states: map[string]string{}, flows: map[string]string{}}
+	previous := oauthStorage
+	oauthStorage = store
+	t.Cleanup(func() { oauthStorage = previous })
+
+	return store
+}
+
+func (s *memoryOAuthStore) SetState(stateID string, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[stateID] = state
+	return nil
+}
+
+func (s *memoryOAuthStore) PopState(stateID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.states[stateID]
+	delete(s.states, stateID)
+	return state, nil
+}
+
+func (s *memoryOAuthStore) SetLoginFlow(flowID string, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flows[flowID] = userID
+	return nil
+}
+
+func (s *memoryOAuthStore) PopLoginFlow(flowID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	userID := s.flows[flowID]
+	delete(s.flows, flowID)
+	return userID, nil
+}
+
+func (s *memoryOAuthStore) GetUserByID(userID string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.ID.Hex() == userID {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryOAuthStore) GetUserByEmail(email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryOAuthStore) CreateUser(email string, username string, hashedPassword string, nickname string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, &models.User{
+		ID:       primitive.NewObjectID(),
+		Email:    email,
+		Username: username,
+		Password: hashedPassword,
+		Nickname: nickname,
+	})
+	return nil
+}
+
+func (s *memoryOAuthStore) GetIdentityByProviderAndSubject(provider string, subject string) (*models.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryOAuthStore) GetIdentityByProviderAndUserID(provider string, userID string) (*models.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.UserID.Hex() == userID {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryOAuthStore) CreateIdentity(identity *models.UserIdentity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	identity.ID = primitive.NewObjectID()
+	s.identities = append(s.identities, identity)
+	return nil
+}
+
+func (s *memoryOAuthStore) UpdateIdentityUsage(id primitive.ObjectID, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, identity := range s.identities {
+		if identity.ID == id {
+			identity.Email = email
+		}
+	}
+	return nil
+}
+
+// create a user with the email in the store
+func createUser(t *testing.T, store *memoryOAuthStore, email string) *models.User {
+	t.Helper()
+
+	if err := store.CreateUser(email, "oauthtest", "password", "oauthtest"); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	user, _ := store.GetUserByEmail(email)
+	return user
+}
+
+// register the fake provider under the name, the provider is removed with the server when the test ends
+func setupFakeProvider(t *testing.T, name string) *oauthtest.Provider {
+	t.Helper()
+
+	fake, err := oauthtest.NewProvider()
+	if err != nil {
+		t.Fatalf("start fake provider: %v", err)
+	}
+	t.Cleanup(fake.Close)
+
+	p, err := oauth.NewOIDCProvider(context.Background(), name, fake.Issuer(), "client-id", "client-secret")
+	if err != nil {
+		t.Fatalf("discover fake provider: %v", err)
+	}
+	oauth.Register(p)
+
+	return fake
+}
+
+// the user consents at the provider, return the state and the code the provider redirects back with
+// challenge and nonce are taken from the authorization url unless the test replaces them
+func authorize(t *testing.T, fake *oauthtest.Provider, authURL string, user oauthtest.User, replace func(params url.Values)) (string, string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization url: %v", err)
+	}
+	params := u.Query()
+	if replace != nil {
+		replace(params)
+	}
+
+	code := fake.Authorize(params.Get("client_id"), params.Get("code_challenge"), params.Get("nonce"), user)
+	return params.Get("state"), code
+}
+
+// sign in or link at the fake provider and return the query of the frontend url the callback redirects to
+func callback(t *testing.T, fake *oauthtest.Provider, userID string, user oauthtest.User, replace func(params url.Values)) url.Values {
+	t.Helper()
+
+	authURL, err := CreateOAuthAuthorizationURL("fake", userID)
+	if err != nil {
+		t.Fatalf("create authorization url: %v", err)
+	}
+	state, code := authorize(t, fake, authURL, user, replace)
+
+	return callbackResult(t, UserOAuthCallback("fake", state, code, ""))
+}
+
+// the query of the frontend url the callback redirects to
+func callbackResult(t *testing.T, redirectURL string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		t.Fatalf("parse callback redirect: %v", err)
+	}
+	return u.Query()
+}
+
+// the user the login flow of the callback signs in
+func flowUser(t *testing.T, store *memoryOAuthStore, result url.Values) *models.User {
+	t.Helper()
+
+	userID, _ := store.PopLoginFlow(result.Get("flow_id"))
+	user, _ := store.GetUserByID(userID)
+	if user == nil {
+		t.Fatalf("expected a login flow, got %v", result)
+	}
+	return user
+}
+
+func TestOAuthCallbackInvalidState(t *testing.T) {
+	setupMemoryStore(t)
+	setupFakeProvider(t, "fake")
+
+	result := callbackResult(t, UserOAuthCallback("fake", "unknown-state", "code", ""))
+	if result.Get("error") != "invalid or expired oauth state" {
+		t.Fatalf("expected an invalid state error, got %v", result)
+	}
+}
+
+func TestOAuthCallbackStateOfAnotherProvider(t *testing.T) {
+	setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+	setupFakeProvider(t, "fake-other")
+
+	authURL, err := CreateOAuthAuthorizationURL("fake", "")
+	if err != nil {
+		t.Fatalf("create authorization url: %v", err)
+	}
+	state, code := authorize(t, fake, authURL, oauthtest.User{Subject: "subject-1"}, nil)
+
+	result := callbackResult(t, UserOAuthCallback("fake-other", state, code, ""))
+	if result.Get("error") != "invalid or expired oauth state" {
+		t.Fatalf("expected an invalid state error, got %v", result)
+	}
+}
+
+func TestOAuthCallbackStateReuse(t *testing.T) {
+	store := setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	user := createUser(t, store, "alice@example.com")
+
+	authURL, err := CreateOAuthAuthorizationURL("fake", "")
+	if err != nil {
+		t.Fatalf("create authorization url: %v", err)
+	}
+	state, code := authorize(t, fake, authURL, oauthtest.User{Subject: "subject-1", Email: user.Email, EmailVerified: true}, nil)
+
+	result := callbackResult(t, UserOAuthCallback("fake", state, code, ""))
+	if result.Get("flow_id") == "" {
+		t.Fatalf("expected a flow id, got %v", result)
+	}
+
+	result = callbackResult(t, UserOAuthCallback("fake", state, code, ""))
+	if result.Get("error") != "invalid or expired oauth state" {
+		t.Fatalf("expected the used state to be rejected, got %v", result)
+	}
+}
+
+func TestOAuthCallbackDenied(t *testing.T) {
+	setupMemoryStore(t)
+	setupFakeProvider(t, "fake")
+
+	authURL, err := CreateOAuthAuthorizationURL("fake", "")
+	if err != nil {
+		t.Fatalf("create authorization url: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+
+	result := callbackResult(t, UserOAuthCallback("fake", u.Query().Get("state"), "", "access_denied"))
+	if result.Get("error") != "authorization was denied" {
+		t.Fatalf("expected the denied authorization to be rejected, got %v", result)
+	}
+}
+
+func TestOAuthCallbackNonceMismatch(t *testing.T) {
+	setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	result := callback(t, fake, "", oauthtest.User{Subject: "subject-1"}, func(params url.Values) {
+		params.Set("nonce", "another-nonce")
+	})
+	if result.Get("error") != "failed to get the user from fake" {
+		t.Fatalf("expected the nonce mismatch to be rejected, got %v", result)
+	}
+}
+
+func TestOAuthCallbackPKCEMismatch(t *testing.T) {
+	setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	result := callback(t, fake, "", oauthtest.User{Subject: "subject-1"}, func(params url.Values) {
+		params.Set("code_challenge", "challenge-of-another-request")
+	})
+	if result.Get("error") != "failed to get the user from fake" {
+		t.Fatalf("expected the pkce mismatch to be rejected, got %v", result)
+	}
+}
+
+func TestOAuthCallbackLinksVerifiedEmail(t *testing.T) {
+	store := setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	user := createUser(t, store, "alice@example.com")
+
+	result := callback(t, fake, "", oauthtest.User{Subject: "subject-1", Email: user.Email, EmailVerified: true}, nil)
+	if signedIn := flowUser(t, store, result); signedIn.ID != user.ID {
+		t.Fatalf("expected %s to sign in, got %s", user.ID.Hex(), signedIn.ID.Hex())
+	}
+
+	identity, _ := store.GetIdentityByProviderAndSubject("fake", "subject-1")
+	if identity == nil || identity.UserID != user.ID {
+		t.Fatalf("expected the account to be linked to %s, got %v", user.ID.Hex(), identity)
+	}
+
+	// the linked account signs in the same user even with another email
+	result = callback(t, fake, "", oauthtest.User{Subject: "subject-1", Email: "other@example.com"}, nil)
+	if signedIn := flowUser(t, store, result); signedIn.ID != user.ID {
+		t.Fatalf("expected %s to sign in, got %s", user.ID.Hex(), signedIn.ID.Hex())
+	}
+}
+
+func TestOAuthCallbackDoesNotLinkUnverifiedEmail(t *testing.T) {
+	store := setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	user := createUser(t, store, "alice@example.com")
+
+	result := callback(t, fake, "", oauthtest.User{Subject: "subject-1", Email: user.Email, EmailVerified: false}, nil)
+	if result.Get("error") != "the email of the fake account is not verified" {
+		t.Fatalf("expected the unverified email to be rejected, got %v", result)
+	}
+
+	if identity, _ := store.GetIdentityByProviderAndSubject("fake", "subject-1"); identity != nil {
+		t.Fatalf("expected the account not to be linked, got %v", identity)
+	}
+}
+
+func TestOAuthCallbackCreatesUser(t *testing.T) {
+	store := setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	result := callback(t, fake, "", oauthtest.User{Subject: "subject-1", Email: "bob@example.com", EmailVerified: true}, nil)
+	user := flowUser(t, store, result)
+	if user.Email != "bob@example.com" {
+		t.Fatalf("expected a user with the email of the account, got %s", user.Email)
+	}
+
+	identity, _ := store.GetIdentityByProviderAndSubject("fake", "subject-1")
+	if identity == nil || identity.UserID != user.ID {
+		t.Fatalf("expected the account to be linked to the new user, got %v", identity)
+	}
+}
+
+func TestOAuthLink(t *testing.T) {
+	store := setupMemoryStore(t)
+	fake := setupFakeProvider(t, "fake")
+
+	user := createUser(t, store, "alice@example.com")
+	other := createUser(t, store, "bob@example.com")
+
+	// the linked account may have another email, the user is logged in
+	result := callback(t, fake, user.ID.Hex(), oauthtest.User{Subject: "subject-1", Email: "alice@provider.example.com"}, nil)
+	if result.Get("linked") != "fake" {
+		t.Fatalf("expected the account to be linked, got %v", result)
+	}
+
+	identity, _ := store.GetIdentityByProviderAndUserID("fake", user.ID.Hex())
+	if identity == nil || identity.Subject != "subject-1" {
+		t.Fatalf("expected the linked account subject-1, got %v", identity)
+	}
+
+	result = callback(t, fake, user.ID.Hex(), oauthtest.User{Subject: "subject-2"}, nil)
+	if result.Get("error") != "another fake account is already linked, unlink it first" {
+		t.Fatalf("expected a second account to be rejected, got %v", result)
+	}
+
+	result = callback(t, fake, other.ID.Hex(), oauthtest.User{Subject: "subject-1"}, nil)
+	if result.Get("error") != "the fake account is linked to another user" {
+		t.Fatalf("expected the account of another user to be rejected, got %v", result)
+	}
+}
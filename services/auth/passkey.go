@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/passkey"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// begin the discoverable login ceremony, return the session id and the options for navigator.credentials.get()
+func UserPasskeyLoginBegin() (map[string]interface{}, error) {
+
+	assertion, session, err := passkey.WebAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	sessionID, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	err = passkey.SaveSession(consts.PASSKEY_LOGIN_SESSION+sessionID, session)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	return map[string]interface{}{
+		"sessionId": sessionID,
+		"options":   assertion,
+	}, nil
+}
+
+// finish the login ceremony and issue the tokens
+func UserPasskeyLoginFinish(request *requests.PasskeyLoginFinishRequest) (*model.LoginResponse, error) {
+
+	session, err := passkey.PopSession(consts.PASSKEY_LOGIN_SESSION + request.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(request.Credential))
+	if err != nil {
+		return nil, errors.New("invalid passkey credential")
+	}
+
+	// the user handle is the user id, see passkey.User.WebAuthnID
+	var passkeyUser *passkey.User
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		if len(userHandle) != 12 {
+			return nil, errors.New("invalid user handle")
+		}
+
+		var userID primitive.ObjectID
+		copy(userID[:], userHandle)
+
+		user, err := repositories.GetUserByID(userID.Hex())
+		if err != nil || user == nil {
+			return nil, errors.New("user not found")
+		}
+
+		credentials, err := repositories.GetPasskeyCredentialsByUserID(userID.Hex())
+		if err != nil {
+			return nil, err
+		}
+
+		passkeyUser = &passkey.User{User: user, Credentials: credentials}
+		return passkeyUser, nil
+	}
+
+	credential, err := passkey.WebAuthn.ValidateDiscoverableLogin(handler, *session, parsedResponse)
+	if err != nil || passkeyUser == nil {
+		return nil, errors.New("invalid passkey credential")
+	}
+
+	// the signature counter went backwards, the authenticator may be cloned
+	if credential.Authenticator.CloneWarning {
+		return nil, errors.New("invalid passkey credential")
+	}
+
+	err = repositories.UpdatePasskeyCredentialUsage(passkey.EncodeCredentialID(credential.ID), int64(credential.Authenticator.SignCount), credential.Flags.BackupState)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	if err := account.CheckUserStatus(passkeyUser.User); err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.HandleLogin(passkeyUser.User, request.Device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{User: passkeyUser.User, Token: token}, nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/mail"
+)
+
+// send a one-time sign in link to the email
+// an unknown email gets the same response so the registered emails can not be enumerated
+func UserEmailLoginWithLink(request *requests.EmailLoginLinkRequest) error {
+
+	// the cooldown starts before the user is looked up, an unknown email is throttled the same way
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_LOGIN_CODE+request.Email); err != nil {
+		return err
+	}
+
+	user, err := repositories.GetUserByEmail(request.Email)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if user == nil {
+		return nil
+	}
+
+	// the flow id is random, the email is kept in redis
+	flowID, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_LOGIN_LINK_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
+
+	link := os.Getenv("FRONTEND_URL") + consts.FRONTEND_LOGIN_ROUTE + "?flow_id=" + flowID
+
+	err = databases.RedisSet(consts.VERIFY_EMAIL_LOGIN_FLOW_ID+flowID, user.Email, consts.VERIFY_EMAIL_LOGIN_LINK_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		return errors.New("try again later")
+	}
+
+	sendVerificationEmailAsync(user.Email, user.Username, mail.VerificationFormTypeLink, mail.VerificationRequestTypeLogin, link, expiredAt)
+
+	return nil
+}
+
+func UserEmailLoginLinkVerify(request *requests.EmailLoginLinkVerifyRequest) (*model.LoginResponse, error) {
+
+	email, err := databases.RedisGet(consts.VERIFY_EMAIL_LOGIN_FLOW_ID + request.FlowId)
+	if err != nil || email == "" {
+		return nil, errors.New("invalid or expired link")
+	}
+
+	// the link can only be used once
+	databases.RedisDel(consts.VERIFY_EMAIL_LOGIN_FLOW_ID + request.FlowId)
+
+	user, err := repositories.GetUserByEmail(email)
+	if err != nil || user == nil {
+		return nil, errors.New("invalid or expired link")
+	}
+
+	return completeLogin(user, request.Device)
+}
+
+// send a six-digit sign in code to the email
+// an unknown email gets the same response so the registered emails can not be enumerated
+func UserEmailLoginWithCode(request *requests.EmailLoginCodeRequest) error {
+
+	// the cooldown starts before the user is looked up, an unknown email is throttled the same way
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_LOGIN_CODE+request.Email); err != nil {
+		return err
+	}
+
+	user, err := repositories.GetUserByEmail(request.Email)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if user == nil {
+		return nil
+	}
+
+	verificationCode, err := GenerateVerificationCode()
+	if err != nil {
+		return errors.New("try again later")
+	}
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_LOGIN_CODE_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
+
+	err = storeEmailVerificationCode(consts.VERIFY_EMAIL_LOGIN_CODE+user.Email, verificationCode, consts.VERIFY_EMAIL_LOGIN_CODE_EXPIRY)
+	if err != nil {
+		return errors.New("try again later")
+	}
+
+	sendVerificationEmailAsync(user.Email, user.Username, mail.VerificationFormTypeCode, mail.VerificationRequestTypeLogin, verificationCode, expiredAt)
+
+	return nil
+}
+
+func UserEmailLoginCodeVerify(request *requests.EmailLoginCodeVerifyRequest) (*model.LoginResponse, error) {
+
+	// the code is burned after too many wrong attempts
+	err := checkEmailVerificationCode(consts.VERIFY_EMAIL_LOGIN_CODE+request.Email, request.Code, consts.VERIFY_EMAIL_LOGIN_CODE_EXPIRY)
+	if err != nil {
+		return nil, err
+	}
+
+	// the code can only be used once
+	clearEmailVerificationCode(consts.VERIFY_EMAIL_LOGIN_CODE + request.Email)
+
+	user, err := repositories.GetUserByEmail(request.Email)
+	if err != nil || user == nil {
+		return nil, errors.New("invalid code")
+	}
+
+	return completeLogin(user, request.Device)
+}
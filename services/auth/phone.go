@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/mail"
+	"gin-auth-mongo/utils/sms"
+)
+
+// send a six-digit code to the phone and keep it in redis under codeKey
+// the caller MUST start the cooldown first, see StartVerificationCodeCooldown
+func SendPhoneVerificationCode(codeKey string, phone string, requestType mail.VerificationRequestType) error {
+
+	verificationCode, err := GenerateVerificationCode()
+	if err != nil {
+		return errors.New("try again later")
+	}
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_PHONE_CODE_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
+
+	err = sms.SendVerificationSMS(phone, requestType, verificationCode, expiredAt)
+	if err != nil {
+		return errors.New("try again later")
+	}
+
+	// a new code resets the attempts
+	databases.RedisDel(consts.VERIFY_PHONE_CODE_ATTEMPTS + codeKey)
+	return databases.RedisSet(codeKey, verificationCode, consts.VERIFY_PHONE_CODE_EXPIRY, datetime.MINUTES)
+}
+
+// check the code stored under codeKey, the code is burned after too many wrong attempts
+// the attempt is counted before the code is compared, so the parallel guesses can not pass the limit
+// the code can only be used once
+func VerifyPhoneVerificationCode(codeKey string, code string) error {
+
+	storedCode, err := databases.RedisGet(codeKey)
+	if err != nil || len(storedCode) != 6 {
+		return errors.New("invalid or expired code")
+	}
+
+	attempts, err := databases.RedisIncr(consts.VERIFY_PHONE_CODE_ATTEMPTS + codeKey)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if attempts == 1 {
+		databases.RedisExpire(consts.VERIFY_PHONE_CODE_ATTEMPTS+codeKey, consts.VERIFY_PHONE_CODE_EXPIRY, datetime.MINUTES)
+	}
+
+	if attempts > consts.VERIFY_PHONE_CODE_MAX_ATTEMPTS || subtle.ConstantTimeCompare([]byte(storedCode), []byte(code)) != 1 {
+		if attempts >= consts.VERIFY_PHONE_CODE_MAX_ATTEMPTS {
+			databases.RedisDel(codeKey)
+			databases.RedisDel(consts.VERIFY_PHONE_CODE_ATTEMPTS + codeKey)
+			return errors.New("too many attempts, please request a new code")
+		}
+		return errors.New("invalid code")
+	}
+
+	databases.RedisDel(codeKey)
+	databases.RedisDel(consts.VERIFY_PHONE_CODE_ATTEMPTS + codeKey)
+
+	return nil
+}
+
+// send a six-digit sign in code to the phone
+// an unknown phone gets the same response so the registered phones can not be enumerated
+func UserPhoneLoginWithCode(request *requests.PhoneLoginCodeRequest) error {
+
+	// the cooldown starts before the user is looked up, an unknown phone is throttled the same way
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodPhone, consts.VERIFY_PHONE_LOGIN_CODE+request.Phone); err != nil {
+		return err
+	}
+
+	user, err := repositories.GetUserByPhone(request.Phone)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if user == nil {
+		return nil
+	}
+
+	return SendPhoneVerificationCode(consts.VERIFY_PHONE_LOGIN_CODE+user.Phone, user.Phone, mail.VerificationRequestTypeLogin)
+}
+
+func UserPhoneLoginCodeVerify(request *requests.PhoneLoginCodeVerifyRequest) (*model.LoginResponse, error) {
+
+	err := VerifyPhoneVerificationCode(consts.VERIFY_PHONE_LOGIN_CODE+request.Phone, request.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := repositories.GetUserByPhone(request.Phone)
+	if err != nil || user == nil {
+		return nil, errors.New("invalid code")
+	}
+
+	return completeLogin(user, request.Device)
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"log"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/mail"
+	"gin-auth-mongo/utils/mfa"
+)
+
+// verify the second factor, the code can be a totp code or a recovery code
+// a matched recovery code is consumed and the user is warned by email of what it was used for
+func VerifySecondFactor(user *models.User, code string, purpose mail.SecondFactorPurpose) bool {
+
+	if step, valid := mfa.ValidateEncryptedTOTPCodeStep(user.TwoFactorSecret, code); valid {
+		return acceptTOTPStep(user.ID.Hex(), step)
+	}
+
+	index := mfa.MatchRecoveryCode(user.RecoveryCodes, code)
+	if index < 0 {
+		return false
+	}
+
+	// make sure the code is only used once even with concurrent requests
+	consumed, err := repositories.ConsumeUserRecoveryCodeByID(user.ID.Hex(), user.RecoveryCodes[index])
+	if err != nil || !consumed {
+		return false
+	}
+
+	remaining := len(user.RecoveryCodes) - 1
+	go func() {
+		if err := mail.SendRecoveryCodeUsedEmail(user.Email, user.Username, purpose, remaining).Error; err != nil {
+			log.Println("Error sending recovery code used email:", err)
+		}
+	}()
+
+	return true
+}
@@ -1,14 +1,13 @@
 package auth
 
 import (
-	"encoding/base64"
+	"encoding/json"
 	"errors"
 
 	// "log"
 
 	// "log"
 	"os"
-	"strings"
 	"time"
 
 	"gin-auth-mongo/databases"
@@ -18,46 +17,51 @@ import (
 	"gin-auth-mongo/utils/datetime"
 
 	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
 	"gin-auth-mongo/utils/mail"
 )
 
-// generate a encoded email and username and expiration time for completion of registration
-func GenerateCompletionRegistrationFlowID(email, username string) (string, string, error) {
-
-	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_REGISTER_LINK_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
-	code := email + "$" + username + "$" + expiredAt
-
-	// base64 encode
-	flowID := base64.RawURLEncoding.EncodeToString([]byte(code))
-	// log.Println("regCode(after encode): ", encodedCode)
-	return flowID, expiredAt, nil
+// the pending registration of a link, the flow id in the link is random and the data is kept in redis
+type registrationFlow struct {
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	ExpiredAt string `json:"expiredAt"`
 }
 
-// decode the completion registration code
-func DecodeCompletionRegistrationFlowID(flowID string) (string, string, string, error) {
+// generate a random flow id for completion of registration and keep the email and username under it
+func GenerateCompletionRegistrationFlowID(email, username string) (string, string, error) {
 
-	decodedCode, err := base64.RawURLEncoding.DecodeString(flowID)
+	flowID, err := jwt.GenerateRefreshToken(32)
 	if err != nil {
-		return "", "", "", err
+		return "", "", err
 	}
-	splitted := strings.Split(string(decodedCode), "$")
-	if len(splitted) != 3 {
-		return "", "", "", errors.New("invalid code")
-	}
-	email := splitted[0]
-	username := splitted[1]
-	expiredAt := splitted[2]
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_REGISTER_LINK_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
 
-	timeExpired, err := time.Parse(consts.DATETIME_FORMAT, expiredAt)
+	data, _ := json.Marshal(registrationFlow{
+		Email:     email,
+		Username:  username,
+		ExpiredAt: expiredAt,
+	})
+	err = databases.RedisSet(consts.VERIFY_EMAIL_REGISTER_FLOW_ID+flowID, string(data), consts.VERIFY_EMAIL_REGISTER_LINK_EXPIRY, datetime.MINUTES)
 	if err != nil {
-		return "", "", "", errors.New("invalid code")
+		return "", "", err
 	}
 
-	if time.Now().After(timeExpired) {
-		return "", "", "", errors.New("code expired")
+	return flowID, expiredAt, nil
+}
+
+// get the pending registration of the flow id, an unknown or expired flow id is invalid
+func getRegistrationFlow(flowID string) (*registrationFlow, error) {
+	data, err := databases.RedisGet(consts.VERIFY_EMAIL_REGISTER_FLOW_ID + flowID)
+	if err != nil || data == "" {
+		return nil, errors.New("invalid or expired link")
 	}
 
-	return email, username, expiredAt, nil
+	var flow registrationFlow
+	if err := json.Unmarshal([]byte(data), &flow); err != nil {
+		return nil, errors.New("invalid or expired link")
+	}
+	return &flow, nil
 }
 
 func UserEmailRegisterWithLink(request *requests.EmailRegisterLinkRequest) error {
@@ -91,11 +95,10 @@ func UserEmailRegisterWithLink(request *requests.EmailRegisterLinkRequest) error
 
 	err = mail.SendVerificationEmail(request.Email, request.Username, mail.VerificationFormTypeLink, mail.VerificationRequestTypeRegister, link, expiredAt).Error
 	if err != nil {
+		databases.RedisDel(consts.VERIFY_EMAIL_REGISTER_FLOW_ID + flowID)
 		return errors.New("try again later")
 	}
 
-	// store the code in redis
-	databases.RedisSet(consts.VERIFY_EMAIL_REGISTER_FLOW_ID+flowID, "1", consts.VERIFY_EMAIL_REGISTER_LINK_EXPIRY, datetime.MINUTES)
 	// to prevent the user from being registered multiple times
 	databases.RedisSet(consts.VERIFY_EMAIL_REGISTER_USERNAME+request.Email, request.Username, consts.VERIFY_EMAIL_REGISTER_LINK_EXPIRY, datetime.MINUTES)
 
@@ -119,45 +122,74 @@ func UserEmailRegisterWithCode(request *requests.EmailRegisterCodeRequest) error
 		return errors.New("email or username already registered")
 	}
 
-	verificationCode, err := GenerateVerificationCode()
-	if err != nil {
-		return errors.New("try again later")
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_REGISTER_CODE+request.Email); err != nil {
+		return err
 	}
-	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
 
-	err = mail.SendVerificationEmail(request.Email, request.Username, mail.VerificationFormTypeCode, mail.VerificationRequestTypeRegister, verificationCode, expiredAt).Error
+	hashedPassword, err := crypto.HashPassword(request.Password)
 	if err != nil {
 		return errors.New("try again later")
 	}
 
-	hashedPassword, err := crypto.HashPassword(request.Password)
-	if err != nil {
-		return errors.New("try again later")
+	if err := sendEmailRegisterCode(request.Email, request.Username); err != nil {
+		return err
 	}
 
-	// store the code in redis
+	// store the pending registration in redis
 	databases.RedisSet(consts.VERIFY_EMAIL_REGISTER_USERNAME+request.Email, request.Username, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY, datetime.MINUTES)
-	databases.RedisSet(consts.VERIFY_EMAIL_REGISTER_CODE+request.Email, verificationCode, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY, datetime.MINUTES)
 	databases.RedisSet(consts.VERIFY_EMAIL_REGISTER_PASSWORD+request.Email, hashedPassword, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY, datetime.MINUTES)
 
-	// TODO: Limit the frequency
 	return nil
 }
 
-func UserEmailRegisterLinkVerify(request *requests.EmailRegisterLinkVerifyRequest) error {
+// send a new code for the pending registration, the registration is kept for another consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY
+func UserEmailRegisterWithCodeResend(request *requests.EmailCodeResendRequest) error {
 
-	// check if the registration code is existing
-	flowID, err := databases.RedisGet(consts.VERIFY_EMAIL_REGISTER_FLOW_ID + request.FlowId)
+	username, errUsername := databases.RedisGet(consts.VERIFY_EMAIL_REGISTER_USERNAME + request.Email)
+	password, errPassword := databases.RedisGet(consts.VERIFY_EMAIL_REGISTER_PASSWORD + request.Email)
+	if errUsername != nil || errPassword != nil || username == "" || password == "" {
+		return errors.New("registration expired, please register again")
+	}
 
-	if err != nil || flowID == "" {
-		return errors.New("invalid flow id")
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_REGISTER_CODE+request.Email); err != nil {
+		return err
 	}
 
-	// decode the registration code
-	email, username, _, err := DecodeCompletionRegistrationFlowID(request.FlowId)
+	if err := sendEmailRegisterCode(request.Email, username); err != nil {
+		return err
+	}
+
+	databases.RedisExpire(consts.VERIFY_EMAIL_REGISTER_USERNAME+request.Email, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY, datetime.MINUTES)
+	databases.RedisExpire(consts.VERIFY_EMAIL_REGISTER_PASSWORD+request.Email, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY, datetime.MINUTES)
+
+	return nil
+}
+
+// send a six-digit code to the email and keep it in redis, a new code replaces the previous one
+func sendEmailRegisterCode(email string, username string) error {
+
+	verificationCode, err := GenerateVerificationCode()
+	if err != nil {
+		return errors.New("try again later")
+	}
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
+
+	err = mail.SendVerificationEmail(email, username, mail.VerificationFormTypeCode, mail.VerificationRequestTypeRegister, verificationCode, expiredAt).Error
+	if err != nil {
+		return errors.New("try again later")
+	}
+
+	return storeEmailVerificationCode(consts.VERIFY_EMAIL_REGISTER_CODE+email, verificationCode, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY)
+}
+
+func UserEmailRegisterLinkVerify(request *requests.EmailRegisterLinkVerifyRequest) error {
+
+	// get the pending registration of the link
+	flow, err := getRegistrationFlow(request.FlowId)
 	if err != nil {
 		return err
 	}
+	email, username := flow.Email, flow.Username
 
 	nickname := request.Nickname
 	if nickname == "" {
@@ -185,15 +217,10 @@ func UserEmailRegisterLinkVerify(request *requests.EmailRegisterLinkVerifyReques
 
 func UserEmailRegisterCodeVerify(request *requests.EmailRegisterCodeVerifyRequest) error {
 
-	// check if the registration code is existing
-	code, err := databases.RedisGet(consts.VERIFY_EMAIL_REGISTER_CODE + request.Email)
-
+	// the code is burned after too many wrong attempts
+	err := checkEmailVerificationCode(consts.VERIFY_EMAIL_REGISTER_CODE+request.Email, request.Code, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY)
 	if err != nil {
-		return errors.New("invalid code")
-	}
-
-	if code != request.Code {
-		return errors.New("invalid code")
+		return err
 	}
 
 	// if the code is correct, get the username and email
@@ -213,8 +240,8 @@ func UserEmailRegisterCodeVerify(request *requests.EmailRegisterCodeVerifyReques
 	}
 
 	// delete the code, username, password from redis
-	databases.RedisDel(consts.VERIFY_EMAIL_REGISTER_CODE + request.Email)
-	databases.RedisDel(consts.VERIFY_EMAIL_REGISTER_USERNAME + username)
+	clearEmailVerificationCode(consts.VERIFY_EMAIL_REGISTER_CODE + request.Email)
+	databases.RedisDel(consts.VERIFY_EMAIL_REGISTER_USERNAME + request.Email)
 	databases.RedisDel(consts.VERIFY_EMAIL_REGISTER_PASSWORD + request.Email)
 
 	return nil
@@ -223,21 +250,15 @@ func UserEmailRegisterCodeVerify(request *requests.EmailRegisterCodeVerifyReques
 // check the registeration code expired status
 func CheckUserEmailRegisterLinkExpired(flowID string) (map[string]string, error) {
 
-	// check if the flow id is existing
-	exist, err := databases.RedisExists(consts.VERIFY_EMAIL_REGISTER_FLOW_ID + flowID)
-	if err != nil || !exist {
-		return nil, errors.New("invalid flow id")
-	}
-
-	// decode the registration code
-	email, username, expiredAt, err := DecodeCompletionRegistrationFlowID(flowID)
+	// get the pending registration of the link
+	flow, err := getRegistrationFlow(flowID)
 	if err != nil {
 		return nil, err
 	}
 
 	return map[string]string{
-		"email":     email,
-		"username":  username,
-		"expiredAt": expiredAt,
+		"email":     flow.Email,
+		"username":  flow.Username,
+		"expiredAt": flow.ExpiredAt,
 	}, nil
 }
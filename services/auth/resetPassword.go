@@ -1,71 +1,115 @@
 package auth
 
 import (
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"os"
-	"strings"
 	"time"
 
 	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
 	"gin-auth-mongo/models/requests"
 	"gin-auth-mongo/repositories"
 	"gin-auth-mongo/utils/consts"
 	"gin-auth-mongo/utils/datetime"
 
 	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
 	"gin-auth-mongo/utils/mail"
 )
 
-func GenerateResetPasswordFlowID(email string) (string, string, error) {
-
-	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_RESET_PWD_LINK_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
-	code := email + "$" + expiredAt
-
-	// base64 encode
-	flowID := base64.RawURLEncoding.EncodeToString([]byte(code))
-	// log.Println("resetCode(after encode): ", encodedCode)
+// the pending password reset of a link, the flow id in the link is random and the data is kept in redis
+type resetPasswordFlow struct {
+	UserID    string `json:"userId"`
+	Email     string `json:"email"`
+	ExpiredAt string `json:"expiredAt"`
+	// the fingerprint of the password when the link is sent, the link is invalid once the password changes
+	PasswordFingerprint string `json:"passwordFingerprint"`
+}
 
-	return flowID, expiredAt, nil
+func passwordFingerprint(hashedPassword string) string {
+	sum := sha256.Sum256([]byte(hashedPassword))
+	return hex.EncodeToString(sum[:])
 }
 
-func DecodeResetPasswordFlowID(flowID string) (string, string, error) {
+// generate a random flow id for resetting the password of the user, the previous link of the user is invalidated
+func GenerateResetPasswordFlowID(user *models.User) (string, string, error) {
 
-	decodedCode, err := base64.RawURLEncoding.DecodeString(flowID)
+	flowID, err := jwt.GenerateRefreshToken(32)
 	if err != nil {
 		return "", "", err
 	}
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_RESET_PWD_LINK_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
 
-	splitted := strings.Split(string(decodedCode), "$")
-	if len(splitted) != 2 {
-		return "", "", errors.New("invalid code")
+	data, _ := json.Marshal(resetPasswordFlow{
+		UserID:              user.ID.Hex(),
+		Email:               user.Email,
+		ExpiredAt:           expiredAt,
+		PasswordFingerprint: passwordFingerprint(user.Password),
+	})
+	err = databases.RedisSet(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID+flowID, string(data), consts.VERIFY_EMAIL_RESET_PWD_LINK_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		return "", "", err
 	}
-	email := splitted[0]
-	expiredAt := splitted[1]
 
-	timeExpired, err := time.Parse(consts.DATETIME_FORMAT, expiredAt)
-	if err != nil {
-		return "", "", errors.New("invalid code")
+	// a newer link replaces the previous one
+	previousFlowID, _ := databases.RedisGet(consts.VERIFY_EMAIL_RESET_PWD_LATEST_FLOW_ID + user.ID.Hex())
+	if previousFlowID != "" {
+		databases.RedisDel(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID + previousFlowID)
 	}
+	databases.RedisSet(consts.VERIFY_EMAIL_RESET_PWD_LATEST_FLOW_ID+user.ID.Hex(), flowID, consts.VERIFY_EMAIL_RESET_PWD_LINK_EXPIRY, datetime.MINUTES)
+
+	return flowID, expiredAt, nil
+}
 
-	if time.Now().After(timeExpired) {
-		return "", "", errors.New("code expired")
+// get the pending password reset of the flow id and its user
+// the link is invalid if it is unknown, expired, replaced by a newer link or the password has changed since
+func getResetPasswordFlow(flowID string) (*resetPasswordFlow, *models.User, error) {
+	data, err := databases.RedisGet(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID + flowID)
+	if err != nil || data == "" {
+		return nil, nil, errors.New("invalid or expired link")
 	}
 
-	return email, expiredAt, nil
+	var flow resetPasswordFlow
+	if err := json.Unmarshal([]byte(data), &flow); err != nil {
+		return nil, nil, errors.New("invalid or expired link")
+	}
+
+	user, err := repositories.GetUserByID(flow.UserID)
+	if err != nil || user == nil || passwordFingerprint(user.Password) != flow.PasswordFingerprint {
+		return nil, nil, errors.New("invalid or expired link")
+	}
+
+	return &flow, user, nil
+}
+
+func clearResetPasswordFlow(flowID string, userID string) {
+	databases.RedisDel(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID + flowID)
+	databases.RedisDel(consts.VERIFY_EMAIL_RESET_PWD_LATEST_FLOW_ID + userID)
 }
 
+// send a reset password link to the email
+// an unknown email gets the same response so the registered emails can not be enumerated
 func UserEmailResetPasswordWithLink(request *requests.EmailPasswordResetLinkRequest) error {
 
+	// the cooldown starts before the user is looked up, an unknown email is throttled the same way
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_RESET_PWD_CODE+request.Email); err != nil {
+		return err
+	}
+
 	// check if the user exists
 	user, err := repositories.GetUserByEmail(request.Email)
-
-	if err != nil || user == nil {
-		return errors.New("invalid email")
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if user == nil {
+		return nil
 	}
 
-	// generate reset password code
-	flowID, expiredAt, err := GenerateResetPasswordFlowID(request.Email)
+	// generate reset password flow id
+	flowID, expiredAt, err := GenerateResetPasswordFlowID(user)
 	if err != nil {
 		return errors.New("try again later")
 	}
@@ -73,64 +117,107 @@ func UserEmailResetPasswordWithLink(request *requests.EmailPasswordResetLinkRequ
 	// generate link
 	link := os.Getenv("FRONTEND_URL") + consts.FRONTEND_RESET_PASSWORD_ROUTE + "?flow_id=" + flowID
 
-	err = mail.SendVerificationEmail(user.Email, user.Username, mail.VerificationFormTypeLink, mail.VerificationRequestTypeResetPassword, link, expiredAt).Error
-	if err != nil {
-		return errors.New("try again later")
-	}
-
-	// store the code in redis
-	databases.RedisSet(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID+flowID, "1", consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY, datetime.MINUTES)
+	sendVerificationEmailAsync(user.Email, user.Username, mail.VerificationFormTypeLink, mail.VerificationRequestTypeResetPassword, link, expiredAt)
 
 	return nil
 }
 
+// send a six-digit reset password code to the email, the new password is kept until the code is verified
+// an unknown email gets the same response so the registered emails can not be enumerated
 func UserEmailResetPasswordWithCode(request *requests.EmailPasswordResetCodeRequest) error {
+
+	// the cooldown starts before the user is looked up, an unknown email is throttled the same way
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_RESET_PWD_CODE+request.Email); err != nil {
+		return err
+	}
+
 	// check if the user exists
 	user, err := repositories.GetUserByEmail(request.Email)
-
-	if err != nil || user == nil {
-		return errors.New("invalid email")
+	if err != nil {
+		return errors.New("please try again later")
 	}
 
+	// the password is hashed whether the user exists or not and the email is sent in the background, so the response time does not tell
 	hashedPassword, err := crypto.HashPassword(request.Password)
 	if err != nil {
 		return errors.New("please try again later")
 	}
+	if user == nil {
+		return nil
+	}
 
-	verficationCode, err := GenerateVerificationCode()
+	if err := sendEmailResetPasswordCode(user.Email, user.Username); err != nil {
+		return err
+	}
+
+	databases.RedisSet(consts.VERIFY_EMAIL_RESET_PWD_PASSWORD+request.Email, hashedPassword, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY, datetime.MINUTES)
+
+	return nil
+}
+
+// send a new code for the pending password reset, the new password is kept for another consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY
+// an unknown email or an expired reset gets the same response
+func UserEmailResetPasswordWithCodeResend(request *requests.EmailCodeResendRequest) error {
+
+	if err := StartVerificationCodeCooldown(mail.VerificationMethodEmail, consts.VERIFY_EMAIL_RESET_PWD_CODE+request.Email); err != nil {
+		return err
+	}
 
+	hashedPassword, err := databases.RedisGet(consts.VERIFY_EMAIL_RESET_PWD_PASSWORD + request.Email)
 	if err != nil {
 		return errors.New("please try again later")
 	}
+	if hashedPassword == "" {
+		return nil
+	}
 
-	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
-
-	err = mail.SendVerificationEmail(user.Email, user.Username, mail.VerificationFormTypeCode, mail.VerificationRequestTypeResetPassword, verficationCode, expiredAt).Error
+	user, err := repositories.GetUserByEmail(request.Email)
 	if err != nil {
 		return errors.New("please try again later")
 	}
+	if user == nil {
+		return nil
+	}
 
-	databases.RedisSet(consts.VERIFY_EMAIL_RESET_PWD_CODE+request.Email, verficationCode, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY, datetime.MINUTES)
-	databases.RedisSet(consts.VERIFY_EMAIL_RESET_PWD_PASSWORD+request.Email, hashedPassword, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY, datetime.MINUTES)
+	if err := sendEmailResetPasswordCode(user.Email, user.Username); err != nil {
+		return err
+	}
+
+	databases.RedisExpire(consts.VERIFY_EMAIL_RESET_PWD_PASSWORD+request.Email, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY, datetime.MINUTES)
 
 	return nil
 }
 
-func UserEmailResetPasswordLinkVerify(request *requests.EmailPasswordResetLinkVerifyRequest) error {
+// keep a six-digit code in redis and send it to the email in the background, a new code replaces the previous one
+func sendEmailResetPasswordCode(email string, username string) error {
 
-	exists, err := databases.RedisGet(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID + request.FlowId)
+	verficationCode, err := GenerateVerificationCode()
+	if err != nil {
+		return errors.New("please try again later")
+	}
 
-	// Maybe the email is not in the redis
-	if err != nil || exists != "1" {
-		return errors.New("invalid code")
+	expiredAt := time.Now().Add(time.Duration(consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY) * time.Minute).Format(consts.DATETIME_FORMAT)
+
+	err = storeEmailVerificationCode(consts.VERIFY_EMAIL_RESET_PWD_CODE+email, verficationCode, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY)
+	if err != nil {
+		return errors.New("please try again later")
 	}
 
-	// decode the reset code
-	email, _, err := DecodeResetPasswordFlowID(request.FlowId)
+	sendVerificationEmailAsync(email, username, mail.VerificationFormTypeCode, mail.VerificationRequestTypeResetPassword, verficationCode, expiredAt)
+
+	return nil
+}
+
+func UserEmailResetPasswordLinkVerify(request *requests.EmailPasswordResetLinkVerifyRequest) error {
+
+	_, user, err := getResetPasswordFlow(request.FlowId)
 	if err != nil {
 		return err
 	}
 
+	// the link can only be used once
+	clearResetPasswordFlow(request.FlowId, user.ID.Hex())
+
 	// hash the password
 	password := request.Password
 	hashedPassword, err := crypto.HashPassword(password)
@@ -138,34 +225,21 @@ func UserEmailResetPasswordLinkVerify(request *requests.EmailPasswordResetLinkVe
 		return err
 	}
 
-	user, err := repositories.GetUserByEmail(email)
-	if err != nil || user == nil {
-		return errors.New("invalid email")
-	}
-
 	// update the password
 	err = repositories.UpdateUserPasswordByID(user.ID.Hex(), hashedPassword)
 	if err != nil {
 		return errors.New("update password failed")
 	}
 
-	// delete the flow id from redis
-	databases.RedisDel(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID + request.FlowId)
-
 	return nil
 }
 
 func UserEmailResetPasswordCodeVerify(request *requests.EmailPasswordResetCodeVerifyRequest) error {
 
-	// ctx := databases.GetRedisContext()
-	// code, err := databases.RedisClient.Get(ctx, consts.VERIFY_EMAIL_RESET_PWD_CODE+request.Email).Result()
-	code, err := databases.RedisGet(consts.VERIFY_EMAIL_RESET_PWD_CODE + request.Email)
-	if err != nil || len(code) != 6 {
-		return errors.New("invalid code")
-	}
-
-	if code != request.Code {
-		return errors.New("invalid code")
+	// the code is burned after too many wrong attempts
+	err := checkEmailVerificationCode(consts.VERIFY_EMAIL_RESET_PWD_CODE+request.Email, request.Code, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY)
+	if err != nil {
+		return err
 	}
 
 	// hashedPassword, err := databases.RedisClient.Get(ctx, consts.VERIFY_EMAIL_RESET_PWD_PASSWORD+request.Email).Result()
@@ -177,7 +251,7 @@ func UserEmailResetPasswordCodeVerify(request *requests.EmailPasswordResetCodeVe
 	// check if the user exists
 	user, err := repositories.GetUserByEmail(request.Email)
 	if err != nil || user == nil {
-		return errors.New("invalid email")
+		return errors.New("invalid code")
 	}
 
 	// update the password
@@ -187,7 +261,7 @@ func UserEmailResetPasswordCodeVerify(request *requests.EmailPasswordResetCodeVe
 	}
 
 	// delete the code and password from redis
-	databases.RedisDel(consts.VERIFY_EMAIL_RESET_PWD_CODE + request.Email)
+	clearEmailVerificationCode(consts.VERIFY_EMAIL_RESET_PWD_CODE + request.Email)
 	databases.RedisDel(consts.VERIFY_EMAIL_RESET_PWD_PASSWORD + request.Email)
 
 	return nil
@@ -196,20 +270,13 @@ func UserEmailResetPasswordCodeVerify(request *requests.EmailPasswordResetCodeVe
 // check the reset password code expired status
 func CheckUserEmailResetPasswordLinkExpired(resetCode string) (map[string]string, error) {
 
-	exists, err := databases.RedisGet(consts.VERIFY_EMAIL_RESET_PWD_FLOW_ID + resetCode)
-
-	// Maybe the email is not in the redis
-	if err != nil || exists != "1" {
-		return nil, errors.New("invalid code")
-	}
-
-	email, expiredAt, err := DecodeResetPasswordFlowID(resetCode)
+	flow, _, err := getResetPasswordFlow(resetCode)
 	if err != nil {
 		return nil, err
 	}
 
 	return map[string]string{
-		"email":     email,
-		"expiredAt": expiredAt,
+		"email":     flow.Email,
+		"expiredAt": flow.ExpiredAt,
 	}, nil
 }
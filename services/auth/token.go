@@ -1,39 +1,103 @@
 package auth
 
 import (
-	"encoding/json"
 	"errors"
 	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
 	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwkmanager"
 	"gin-auth-mongo/utils/jwt"
-	"os"
-
-	"github.com/square/go-jose/v3"
+	"log"
+	"time"
 )
 
-func RefreshToken(token string) (*model.AccessToken, error) {
+// rotate the refresh token, return the access token and the next refresh token
+// the access token (may be expired) MUST be issued with the refresh token: same user, same family, and the device MUST match
+// a rotated refresh token presented again has been stolen or replayed, the whole family is revoked
+func RefreshToken(request *requests.RefreshTokenRequest) (*model.AccessToken, error) {
+
+	tokenHash := crypto.HashToken(request.RefreshToken)
 
 	// check if the token is valid
-	refreshToken, err := repositories.GetRefreshTokenByToken(token)
+	refreshToken, err := repositories.GetRefreshTokenByTokenHash(tokenHash)
 	if err != nil || refreshToken == nil {
-		return nil, errors.New("invalid refresh token1")
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if refreshToken.RotatedAt != "" {
+		revokeRefreshTokenFamily(refreshToken)
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// check if the token is expired
+	expiredAt, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, refreshToken.ExpiredAt, time.Local)
+	if err != nil || time.Now().After(expiredAt) {
+		repositories.DeleteRefreshTokenByFamilyID(refreshToken.FamilyID)
+		jwt.RevokeFamilyAccessTokens(refreshToken.FamilyID)
+		return nil, errors.New("refresh token expired")
+	}
+
+	// check if the access token is bound to the refresh token, the expiry of the access token is not checked
+	claims, err := jwt.ParseJWTClaims(request.AccessToken)
+	if err != nil {
+		return nil, errors.New("invalid access token")
+	}
+	subject, _ := claims["sub"].(string)
+	familyID, _ := claims["fid"].(string)
+	if subject != refreshToken.UserID.Hex() || familyID != refreshToken.FamilyID || request.Device != refreshToken.Device {
+		return nil, errors.New("invalid refresh token")
 	}
 
 	// check if the user exists
 	user, err := repositories.GetUserByID(refreshToken.UserID.Hex())
 	if err != nil || user == nil {
-		return nil, errors.New("invalid refresh token2")
+		return nil, errors.New("invalid refresh token")
+	}
+	if err := account.CheckUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	// another request has rotated the token at the same time
+	rotated, err := repositories.RotateRefreshTokenByTokenHash(tokenHash)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+	if !rotated {
+		revokeRefreshTokenFamily(refreshToken)
+		return nil, errors.New("invalid refresh token")
 	}
 
 	// generate new token
-	accessToken, err := jwt.HandleRefreshToken(user)
+	accessToken, err := jwt.HandleRefreshToken(user, refreshToken)
 	if err != nil {
-		return nil, errors.New("invalid refresh token3")
+		return nil, errors.New("try again later")
 	}
 
 	return accessToken, nil
 }
 
+// revoke all the refresh tokens and the access tokens of the login and record the reuse
+// the access tokens issued to the attacker are revoked as well
+func revokeRefreshTokenFamily(refreshToken *models.UserRefreshToken) {
+	log.Printf("Refresh token reuse detected, user: %s, family: %s", refreshToken.UserID.Hex(), refreshToken.FamilyID)
+
+	if err := repositories.DeleteRefreshTokenByFamilyID(refreshToken.FamilyID); err != nil {
+		log.Printf("Error revoking refresh token family: %v", err)
+	}
+	if err := jwt.RevokeFamilyAccessTokens(refreshToken.FamilyID); err != nil {
+		log.Printf("Error revoking access tokens of the family: %v", err)
+	}
+
+	err := repositories.CreateSecurityEvent(refreshToken.UserID, consts.SECURITY_EVENT_REFRESH_TOKEN_REUSE, refreshToken.Device, "a rotated refresh token was used, all the tokens of the login are revoked")
+	if err != nil {
+		log.Printf("Error recording security event: %v", err)
+	}
+}
+
 func GetTokenInfo(token string) (map[string]interface{}, error) {
 	// parse the token
 	parsedJWT, err := jwt.ParseToken(token)
@@ -46,30 +110,15 @@ func GetTokenInfo(token string) (map[string]interface{}, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	// load public key
-	publicJWKs := make([]jose.JSONWebKey, 0)
-	data, err := os.ReadFile(".public/keys.json")
+	// get the public jwk of the kid, the alg in the header MUST be the alg of the key
+	publicJWK, err := jwkmanager.GetVerificationKey(parsedJWT.Headers[0].KeyID, parsedJWT.Headers[0].Algorithm)
 	if err != nil {
-		return nil, err
-	}
-
-	// parse public keys
-	if err := json.Unmarshal(data, &publicJWKs); err != nil {
-		return nil, err
-	}
-
-	JWKs := jose.JSONWebKeySet{
-		Keys: publicJWKs,
-	}
-	publicJWK := JWKs.Key(parsedJWT.Headers[0].KeyID)
-
-	if len(publicJWK) <= 0 {
 		return nil, errors.New("invalid token")
 	}
 
 	// Extract the claims
 	allClaims := make(map[string]interface{})
-	if err := parsedJWT.Claims(publicJWK[0].Key, &allClaims); err != nil {
+	if err := parsedJWT.Claims(publicJWK.Key, &allClaims); err != nil {
 		return nil, err
 	}
 
@@ -79,9 +128,20 @@ func GetTokenInfo(token string) (map[string]interface{}, error) {
 }
 
 func DeleteRefreshTokenByToken(token string) error {
-	return repositories.DeleteRefreshTokenByToken(token)
+	return repositories.DeleteRefreshTokenByTokenHash(crypto.HashToken(token))
 }
 
 func DeleteRefreshTokenByUserID(userID string) error {
 	return repositories.DeleteRefreshTokenByUserID(userID)
 }
+
+// record the client of the session, shown in the session list of the user
+// the login is not failed if it can not be recorded
+func RecordSessionClient(refreshToken string, ip string, userAgent string) {
+	if refreshToken == "" {
+		return
+	}
+	if err := repositories.UpdateRefreshTokenClientByTokenHash(crypto.HashToken(refreshToken), ip, userAgent); err != nil {
+		log.Printf("Failed to record the session client: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/mail"
+)
+
+// create a short-lived challenge which must be verified with the second factor before the tokens are issued
+func createMfaChallenge(user *models.User, device string) (*model.MfaChallenge, error) {
+
+	mfaToken, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// user id and device are needed to finish the login
+	err = databases.RedisSet(consts.MFA_CHALLENGE_TOKEN+mfaToken, user.ID.Hex()+"$"+device, consts.MFA_CHALLENGE_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		return nil, err
+	}
+
+	expiredAt := time.Now().Add(time.Duration(consts.MFA_CHALLENGE_EXPIRY) * time.Minute).Format(consts.DATETIME_NANO_FORMAT)
+
+	return &model.MfaChallenge{
+		MfaToken:       mfaToken,
+		MfaTokenExpiry: expiredAt,
+		Methods:        []string{"totp", "recovery_code"},
+	}, nil
+}
+
+// get the user and device from the challenge
+func getMfaChallenge(mfaToken string) (*models.User, string, error) {
+
+	value, err := databases.RedisGet(consts.MFA_CHALLENGE_TOKEN + mfaToken)
+	if err != nil || value == "" {
+		return nil, "", errors.New("invalid or expired mfa token")
+	}
+
+	splitted := strings.SplitN(value, "$", 2)
+	if len(splitted) != 2 {
+		return nil, "", errors.New("invalid or expired mfa token")
+	}
+
+	user, err := repositories.GetUserByID(splitted[0])
+	if err != nil || user == nil || !user.TwoFactorEnabled {
+		return nil, "", errors.New("invalid or expired mfa token")
+	}
+
+	return user, splitted[1], nil
+}
+
+// delete the challenge and its attempt counter
+func clearMfaChallenge(mfaToken string) {
+	databases.RedisDel(consts.MFA_CHALLENGE_TOKEN + mfaToken)
+	databases.RedisDel(consts.MFA_CHALLENGE_ATTEMPTS + mfaToken)
+}
+
+// count a failed attempt, the challenge is burned after too many failures
+func failMfaChallenge(mfaToken string) error {
+	attempts, err := databases.RedisIncr(consts.MFA_CHALLENGE_ATTEMPTS + mfaToken)
+	if err != nil {
+		return errors.New("try again later")
+	}
+
+	if attempts == 1 {
+		databases.RedisExpire(consts.MFA_CHALLENGE_ATTEMPTS+mfaToken, consts.MFA_CHALLENGE_EXPIRY, datetime.MINUTES)
+	}
+
+	if attempts >= consts.MFA_CHALLENGE_MAX_ATTEMPTS {
+		clearMfaChallenge(mfaToken)
+		return errors.New("too many attempts, please login again")
+	}
+
+	return errors.New("invalid code")
+}
+
+// a totp code is accepted once, the codes of the last accepted time step and the steps before are rejected
+// the code of a step is claimed with SETNX so the concurrent requests with the same code are rejected as well
+func acceptTOTPStep(userID string, step int64) bool {
+	last, err := databases.RedisGet(consts.MFA_TOTP_LAST_STEP + userID)
+	if err != nil {
+		return false
+	}
+	if lastStep, err := strconv.ParseInt(last, 10, 64); err == nil && step <= lastStep {
+		return false
+	}
+
+	// a code is valid for at most three periods with the clock skew
+	expiry := 3 * consts.MFA_TOTP_PERIOD
+	claimed, err := databases.RedisSetNX(consts.MFA_TOTP_USED_STEP+userID+":"+strconv.FormatInt(step, 10), "1", expiry, datetime.SECONDS)
+	if err != nil || !claimed {
+		return false
+	}
+
+	databases.RedisSet(consts.MFA_TOTP_LAST_STEP+userID, strconv.FormatInt(step, 10), expiry, datetime.SECONDS)
+	return true
+}
+
+// reserve a second factor attempt of the user before the code is verified
+// the attempts are counted per user across the challenges, a correct password does not reset them
+func reserveSecondFactorAttempt(user *models.User) (int64, error) {
+	userID := user.ID.Hex()
+
+	attempts, err := databases.RedisIncr(consts.MFA_FAILED_ATTEMPTS + userID)
+	if err != nil {
+		return 0, errors.New("try again later")
+	}
+	databases.RedisExpire(consts.MFA_FAILED_ATTEMPTS+userID, consts.LOGIN_FAILED_WINDOW, datetime.MINUTES)
+
+	locked, err := databases.RedisExists(consts.LOGIN_LOCKOUT + userID)
+	if err != nil {
+		return 0, errors.New("try again later")
+	}
+	if locked {
+		return 0, errTooManyFailedLogins
+	}
+
+	if attempts > consts.LOGIN_LOCKOUT_THRESHOLD {
+		lockSecondFactorAttempts(user)
+		return 0, errTooManyFailedLogins
+	}
+
+	return attempts, nil
+}
+
+// too many wrong codes lock the account, the same lockout as the failed logins
+func lockSecondFactorAttempts(user *models.User) {
+	lockLoginAttempts(user.ID.Hex(), user)
+	databases.RedisDel(consts.MFA_FAILED_ATTEMPTS + user.ID.Hex())
+}
+
+// verify the second factor (totp or recovery code) and issue the tokens
+func UserLoginTwoFactorVerify(request *requests.TwoFactorLoginVerifyRequest) (*model.LoginResponse, error) {
+
+	user, device, err := getMfaChallenge(request.MfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := reserveSecondFactorAttempt(user)
+	if err != nil {
+		clearMfaChallenge(request.MfaToken)
+		return nil, err
+	}
+
+	if !VerifySecondFactor(user, request.Code, mail.SecondFactorPurposeLogin) {
+		if attempts >= consts.LOGIN_LOCKOUT_THRESHOLD {
+			lockSecondFactorAttempts(user)
+			clearMfaChallenge(request.MfaToken)
+			return nil, errTooManyFailedLogins
+		}
+		return nil, failMfaChallenge(request.MfaToken)
+	}
+
+	// the challenge can only be used once
+	clearMfaChallenge(request.MfaToken)
+	databases.RedisDel(consts.MFA_FAILED_ATTEMPTS + user.ID.Hex())
+
+	if err := account.CheckUserStatus(user); err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.HandleLogin(user, device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{User: user, Token: token}, nil
+}
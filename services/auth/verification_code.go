@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/mail"
+)
+
+// allow one code under codeKey per cooldown of the method, eg: consts.VERIFY_EMAIL_CODE_COOLDOWN_EXPIRY
+// the link of the same request shares the cooldown of the code, so the email can not be flooded with either
+// the callers start the cooldown before the user is looked up, so the response does not tell if the email or phone is registered
+func StartVerificationCodeCooldown(method mail.VerificationMethod, codeKey string) error {
+	prefix, expiry := consts.VERIFY_EMAIL_CODE_COOLDOWN, consts.VERIFY_EMAIL_CODE_COOLDOWN_EXPIRY
+	if method == mail.VerificationMethodPhone {
+		prefix, expiry = consts.VERIFY_PHONE_CODE_COOLDOWN, consts.VERIFY_PHONE_CODE_COOLDOWN_EXPIRY
+	}
+
+	started, err := databases.RedisSetNX(prefix+codeKey, "1", expiry, datetime.SECONDS)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if !started {
+		return errors.New("please wait before requesting again")
+	}
+	return nil
+}
+
+// send the verification email in the background, so the response time does not tell if the email is registered
+func sendVerificationEmailAsync(email string, username string, formType mail.VerificationFormType, requestType mail.VerificationRequestType, message string, expiredAt string) {
+	go func() {
+		if err := mail.SendVerificationEmail(email, username, formType, requestType, message, expiredAt).Error; err != nil {
+			log.Println("Error sending verification email:", err)
+		}
+	}()
+}
+
+// keep the code under codeKey, a new code resets the attempts
+func storeEmailVerificationCode(codeKey string, code string, expiry int) error {
+	databases.RedisDel(consts.VERIFY_EMAIL_CODE_ATTEMPTS + codeKey)
+	return databases.RedisSet(codeKey, code, expiry, datetime.MINUTES)
+}
+
+// check the code stored under codeKey, the code is burned after too many wrong attempts
+// the attempt is counted before the code is compared, so the parallel guesses can not pass the limit
+// the caller MUST clear the code once it is used
+func checkEmailVerificationCode(codeKey string, code string, expiry int) error {
+
+	storedCode, err := databases.RedisGet(codeKey)
+	if err != nil || len(storedCode) != 6 {
+		return errors.New("invalid or expired code")
+	}
+
+	attempts, err := databases.RedisIncr(consts.VERIFY_EMAIL_CODE_ATTEMPTS + codeKey)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if attempts == 1 {
+		databases.RedisExpire(consts.VERIFY_EMAIL_CODE_ATTEMPTS+codeKey, expiry, datetime.MINUTES)
+	}
+	if attempts > consts.VERIFY_EMAIL_CODE_MAX_ATTEMPTS {
+		clearEmailVerificationCode(codeKey)
+		return errors.New("too many attempts, please request a new code")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(storedCode), []byte(code)) != 1 {
+		if attempts == consts.VERIFY_EMAIL_CODE_MAX_ATTEMPTS {
+			clearEmailVerificationCode(codeKey)
+			return errors.New("too many attempts, please request a new code")
+		}
+		return errors.New("invalid code")
+	}
+
+	return nil
+}
+
+func clearEmailVerificationCode(codeKey string) {
+	databases.RedisDel(codeKey)
+	databases.RedisDel(consts.VERIFY_EMAIL_CODE_ATTEMPTS + codeKey)
+}
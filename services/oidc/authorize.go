@@ -0,0 +1,231 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/jwt"
+)
+
+// authorizeRequest is kept in redis until the user approves or denies it on the consent screen
+type authorizeRequest struct {
+	ClientID      string   `json:"clientId"`
+	RedirectURI   string   `json:"redirectUri"`
+	Scopes        []string `json:"scopes"`
+	State         string   `json:"state"`
+	Nonce         string   `json:"nonce"`
+	CodeChallenge string   `json:"codeChallenge"`
+}
+
+// authorizationCode is kept in redis until the client exchanges it at the token endpoint
+type authorizationCode struct {
+	ClientID      string   `json:"clientId"`
+	UserID        string   `json:"userId"`
+	RedirectURI   string   `json:"redirectUri"`
+	Scopes        []string `json:"scopes"`
+	Nonce         string   `json:"nonce"`
+	CodeChallenge string   `json:"codeChallenge"`
+}
+
+// ConsentScope is a requested scope shown on the consent screen
+type ConsentScope struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// Consent is the data of the consent screen
+type Consent struct {
+	RequestID   string         `json:"requestId"`
+	ClientID    string         `json:"clientId"`
+	ClientName  string         `json:"clientName"`
+	RedirectURI string         `json:"redirectUri"`
+	Scopes      []ConsentScope `json:"scopes"`
+	Consented   bool           `json:"consented"` // all the scopes were granted before, the frontend may skip the screen
+}
+
+func errorRedirect(redirectURI string, state string, err *OAuthError) string {
+	query := url.Values{"error": {err.Code}}
+	if err.Description != "" {
+		query.Set("error_description", err.Description)
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	return appendQuery(redirectURI, query)
+}
+
+// validate the authorization request and return the url of the consent screen
+// an invalid client or redirect uri is returned as error, the others are redirected to the client
+func Authorize(request *requests.OIDCAuthorizeRequest) (string, *OAuthError) {
+
+	client, err := repositories.GetOAuthClientByID(request.ClientId)
+	if err != nil || client == nil {
+		return "", invalidRequest("invalid client_id")
+	}
+
+	// the redirect uri MUST match exactly, it may be omitted if only one is registered
+	redirectURI := request.RedirectUri
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return "", invalidRequest("invalid redirect_uri")
+	}
+
+	if request.ResponseType != "code" {
+		return errorRedirect(redirectURI, request.State, newOAuthError(http.StatusBadRequest, "unsupported_response_type", "response_type must be code")), nil
+	}
+
+	scopes := parseScopes(request.Scope)
+	if !slices.Contains(scopes, "openid") {
+		return errorRedirect(redirectURI, request.State, newOAuthError(http.StatusBadRequest, "invalid_scope", "the openid scope is required")), nil
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return errorRedirect(redirectURI, request.State, newOAuthError(http.StatusBadRequest, "invalid_scope", "scope "+scope+" is not allowed")), nil
+		}
+	}
+
+	// pkce, only S256 is supported
+	if request.CodeChallenge != "" && request.CodeChallengeMethod != "S256" {
+		return errorRedirect(redirectURI, request.State, invalidRequest("code_challenge_method must be S256")), nil
+	}
+	if request.CodeChallenge == "" && client.Public {
+		return errorRedirect(redirectURI, request.State, invalidRequest("code_challenge is required for public clients")), nil
+	}
+
+	requestID, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return errorRedirect(redirectURI, request.State, newOAuthError(http.StatusInternalServerError, "server_error", "")), nil
+	}
+
+	data, _ := json.Marshal(authorizeRequest{
+		ClientID:      request.ClientId,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		State:         request.State,
+		Nonce:         request.Nonce,
+		CodeChallenge: request.CodeChallenge,
+	})
+	err = databases.RedisSet(consts.OIDC_AUTHORIZE_REQUEST+requestID, string(data), consts.OIDC_AUTHORIZE_REQUEST_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		return errorRedirect(redirectURI, request.State, newOAuthError(http.StatusInternalServerError, "server_error", "")), nil
+	}
+
+	return os.Getenv("FRONTEND_URL") + consts.FRONTEND_OIDC_CONSENT_ROUTE + "?" + url.Values{"request_id": {requestID}}.Encode(), nil
+}
+
+func getAuthorizeRequest(requestID string) (*authorizeRequest, error) {
+	data, err := databases.RedisGet(consts.OIDC_AUTHORIZE_REQUEST + requestID)
+	if err != nil || data == "" {
+		return nil, errors.New("invalid or expired authorization request")
+	}
+
+	var request authorizeRequest
+	if err := json.Unmarshal([]byte(data), &request); err != nil {
+		return nil, errors.New("invalid or expired authorization request")
+	}
+	return &request, nil
+}
+
+// get the data of the consent screen
+func GetConsent(userID string, requestID string) (*Consent, error) {
+
+	request, err := getAuthorizeRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := repositories.GetOAuthClientByID(request.ClientID)
+	if err != nil || client == nil {
+		return nil, errors.New("invalid or expired authorization request")
+	}
+
+	scopes := make([]ConsentScope, 0, len(request.Scopes))
+	for _, scope := range request.Scopes {
+		scopes = append(scopes, ConsentScope{Scope: scope, Description: scopeDescriptions[scope]})
+	}
+
+	consented := false
+	consent, err := repositories.GetOAuthConsent(userID, request.ClientID)
+	if err == nil && consent != nil {
+		consented = true
+		for _, scope := range request.Scopes {
+			if !slices.Contains(consent.Scopes, scope) {
+				consented = false
+				break
+			}
+		}
+	}
+
+	return &Consent{
+		RequestID:   requestID,
+		ClientID:    request.ClientID,
+		ClientName:  client.Name,
+		RedirectURI: request.RedirectURI,
+		Scopes:      scopes,
+		Consented:   consented,
+	}, nil
+}
+
+// approve or deny the authorization request, return the url to redirect the user back to the client
+func SubmitConsent(userID string, requestID string, request *requests.OIDCConsentRequest) (string, error) {
+
+	authRequest, err := getAuthorizeRequest(requestID)
+	if err != nil {
+		return "", err
+	}
+
+	// the request can only be used once
+	databases.RedisDel(consts.OIDC_AUTHORIZE_REQUEST + requestID)
+
+	if !request.Approve {
+		return errorRedirect(authRequest.RedirectURI, authRequest.State, newOAuthError(http.StatusBadRequest, "access_denied", "the user denied the request")), nil
+	}
+
+	code, err := jwt.GenerateRefreshToken(32)
+	if err != nil {
+		return "", errors.New("try again later")
+	}
+
+	data, _ := json.Marshal(authorizationCode{
+		ClientID:      authRequest.ClientID,
+		UserID:        userID,
+		RedirectURI:   authRequest.RedirectURI,
+		Scopes:        authRequest.Scopes,
+		Nonce:         authRequest.Nonce,
+		CodeChallenge: authRequest.CodeChallenge,
+	})
+	err = databases.RedisSet(consts.OIDC_AUTH_CODE+code, string(data), consts.OIDC_AUTH_CODE_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		return "", errors.New("try again later")
+	}
+
+	// remember the granted scopes
+	grantedScopes := authRequest.Scopes
+	consent, err := repositories.GetOAuthConsent(userID, authRequest.ClientID)
+	if err == nil && consent != nil {
+		for _, scope := range consent.Scopes {
+			if !slices.Contains(grantedScopes, scope) {
+				grantedScopes = append(grantedScopes, scope)
+			}
+		}
+	}
+	repositories.UpsertOAuthConsent(userID, authRequest.ClientID, grantedScopes)
+
+	query := url.Values{"code": {code}}
+	if authRequest.State != "" {
+		query.Set("state", authRequest.State)
+	}
+
+	return appendQuery(authRequest.RedirectURI, query), nil
+}
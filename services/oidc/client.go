@@ -0,0 +1,134 @@
+package oidc
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
+)
+
+// RegisteredClient is returned once on registration, the secret can not be retrieved later
+type RegisteredClient struct {
+	*models.OAuthClient
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// the redirect uri MUST be absolute and MUST NOT contain a fragment
+func validateRedirectURI(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return false
+	}
+	return u.Scheme != "" && (u.Host != "" || u.Opaque != "" || u.Path != "") && u.Fragment == ""
+}
+
+// register a client, a confidential client gets a secret
+func CreateOAuthClient(ownerID string, request *requests.OIDCClientCreateRequest) (*RegisteredClient, error) {
+
+	owner, err := repositories.GetUserByID(ownerID)
+	if err != nil || owner == nil {
+		return nil, errors.New("user not found")
+	}
+
+	for _, redirectURI := range request.RedirectUris {
+		if !validateRedirectURI(redirectURI) {
+			return nil, errors.New("invalid redirect uri: " + redirectURI)
+		}
+	}
+
+	scopes := request.Scopes
+	if len(scopes) == 0 {
+		scopes = consts.OIDC_SUPPORTED_SCOPES
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(consts.OIDC_SUPPORTED_SCOPES, scope) {
+			return nil, errors.New("unsupported scope: " + scope)
+		}
+	}
+	if !slices.Contains(scopes, "openid") {
+		return nil, errors.New("the openid scope is required")
+	}
+
+	client := &models.OAuthClient{
+		OwnerID:      owner.ID,
+		Name:         request.Name,
+		Public:       request.Public,
+		RedirectURIs: request.RedirectUris,
+		Scopes:       scopes,
+	}
+
+	var secret string
+	if !request.Public {
+		secret, err = jwt.GenerateRefreshToken(32)
+		if err != nil {
+			return nil, errors.New("try again later")
+		}
+		client.SecretHash, err = crypto.HashPassword(secret)
+		if err != nil {
+			return nil, errors.New("try again later")
+		}
+	}
+
+	err = repositories.CreateOAuthClient(client)
+	if err != nil {
+		return nil, errors.New("register client failed")
+	}
+
+	return &RegisteredClient{OAuthClient: client, ClientSecret: secret}, nil
+}
+
+func GetOAuthClients(ownerID string) ([]models.OAuthClient, error) {
+	clients, err := repositories.GetOAuthClientsByOwnerID(ownerID)
+	if err != nil {
+		return nil, errors.New("get clients failed")
+	}
+	if clients == nil {
+		clients = []models.OAuthClient{}
+	}
+	return clients, nil
+}
+
+// delete the client and the consents granted to it
+func DeleteOAuthClient(ownerID string, clientID string) error {
+
+	client, err := repositories.GetOAuthClientByID(clientID)
+	if err != nil || client == nil || client.OwnerID.Hex() != ownerID {
+		return errors.New("client not found")
+	}
+
+	err = repositories.DeleteOAuthClientByIDAndOwnerID(clientID, ownerID)
+	if err != nil {
+		return errors.New("delete client failed")
+	}
+
+	repositories.DeleteOAuthConsentsByClientID(clientID)
+
+	return nil
+}
+
+// authenticate the client at the token endpoint, the public clients have no secret
+func authenticateClient(clientID string, clientSecret string) (*models.OAuthClient, *OAuthError) {
+
+	client, err := repositories.GetOAuthClientByID(clientID)
+	if err != nil || client == nil {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	}
+
+	if client.Public {
+		return client, nil
+	}
+
+	match, err := crypto.VerifyPassword(clientSecret, client.SecretHash)
+	if err != nil || !match {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	}
+
+	return client, nil
+}
@@ -0,0 +1,72 @@
+package oidc
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
+)
+
+// authenticate the service account at the token endpoint, a disabled account can not get tokens
+func authenticateServiceAccount(clientID string, clientSecret string) (*models.ServiceAccount, *OAuthError) {
+
+	invalidClient := newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+
+	account, err := repositories.GetServiceAccountByID(clientID)
+	if err != nil || account == nil || account.Disabled {
+		return nil, invalidClient
+	}
+
+	match, err := crypto.VerifyPassword(clientSecret, account.SecretHash)
+	if err != nil || !match {
+		return nil, invalidClient
+	}
+
+	return account, nil
+}
+
+// issue the access token of a service account, there is no user and no refresh token
+// all the allowed scopes are granted if no scope is requested
+// https://datatracker.ietf.org/doc/html/rfc6749#section-4.4
+func ClientCredentialsToken(request *requests.OIDCTokenRequest) (*TokenResponse, *OAuthError) {
+
+	if request.GrantType != "client_credentials" {
+		return nil, newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "grant_type must be client_credentials")
+	}
+
+	account, oauthErr := authenticateServiceAccount(request.ClientId, request.ClientSecret)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	scopes := parseScopes(request.Scope)
+	if len(scopes) == 0 {
+		scopes = account.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(account.Scopes, scope) {
+			return nil, newOAuthError(http.StatusBadRequest, "invalid_scope", "the scope is not allowed: "+scope)
+		}
+	}
+
+	accessToken, err := jwt.GenerateServiceAccountAccessToken(account.ID.Hex(), scopes, time.Now())
+	if err != nil {
+		return nil, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+
+	repositories.UpdateServiceAccountLastUsed(account.ID)
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   consts.SERVICE_ACCOUNT_ACCESS_TOKEN_EXPIRY * 60,
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
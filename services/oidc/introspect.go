@@ -0,0 +1,189 @@
+package oidc
+
+import (
+	"net/http"
+	"time"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
+)
+
+// IntrospectionResponse is the response of the introspection endpoint, only active is returned for an inactive token
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2.2
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"` // access_token or refresh_token
+}
+
+// get the claims of an active access token, nil if the token is invalid, expired or revoked
+// the access tokens of this server and of the oidc clients are both accepted, the id tokens are not access tokens
+func activeAccessTokenClaims(token string) map[string]interface{} {
+	claims, err := jwt.ParseJWTClaims(token)
+	if err != nil || claims["token_use"] == consts.TOKEN_USE_ID {
+		return nil
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Unix() > int64(exp) {
+		return nil
+	}
+
+	if revoked, err := jwt.IsAccessTokenRevoked(claims); err != nil || revoked {
+		return nil
+	}
+
+	return claims
+}
+
+// get an active refresh token, nil if the token is unknown, rotated or expired
+func activeRefreshToken(token string) *models.UserRefreshToken {
+	refreshToken, err := repositories.GetRefreshTokenByTokenHash(crypto.HashToken(token))
+	if err != nil || refreshToken == nil || refreshToken.RotatedAt != "" {
+		return nil
+	}
+
+	expiredAt, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, refreshToken.ExpiredAt, time.Local)
+	if err != nil || time.Now().After(expiredAt) {
+		return nil
+	}
+
+	return refreshToken
+}
+
+// the refresh tokens are opaque, a jwt is tried as an access token first unless the hint says otherwise
+func lookupsByHint(hint string) []string {
+	if hint == "refresh_token" {
+		return []string{"refresh_token", "access_token"}
+	}
+	return []string{"access_token", "refresh_token"}
+}
+
+// tell the resource server whether the token is active
+// only the confidential clients can introspect, eg: the api gateway
+func Introspect(request *requests.OIDCTokenIntrospectRequest) (*IntrospectionResponse, *OAuthError) {
+
+	client, oauthErr := authenticateClient(request.ClientId, request.ClientSecret)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	if client.Public {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "only the confidential clients can introspect tokens")
+	}
+
+	if request.Token == "" {
+		return nil, invalidRequest("token is required")
+	}
+
+	for _, tokenType := range lookupsByHint(request.TokenTypeHint) {
+		switch tokenType {
+		case "access_token":
+			claims := activeAccessTokenClaims(request.Token)
+			if claims == nil {
+				continue
+			}
+
+			response := &IntrospectionResponse{Active: true, TokenType: "access_token"}
+			response.Sub, _ = claims["sub"].(string)
+			response.Jti, _ = claims["jti"].(string)
+			response.Iss, _ = claims["iss"].(string)
+			response.ClientID, _ = claims["client_id"].(string)
+			response.Scope, _ = claims["scope"].(string)
+			if exp, ok := claims["exp"].(float64); ok {
+				response.Exp = int64(exp)
+			}
+			if iat, ok := claims["iat"].(float64); ok {
+				response.Iat = int64(iat)
+			}
+			return response, nil
+
+		case "refresh_token":
+			refreshToken := activeRefreshToken(request.Token)
+			if refreshToken == nil {
+				continue
+			}
+
+			expiredAt, _ := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, refreshToken.ExpiredAt, time.Local)
+			return &IntrospectionResponse{
+				Active:    true,
+				Sub:       refreshToken.UserID.Hex(),
+				Exp:       expiredAt.Unix(),
+				Iat:       refreshToken.ID.Timestamp().Unix(),
+				Iss:       consts.JWT_ISSUER,
+				TokenType: "refresh_token",
+			}, nil
+		}
+	}
+
+	return &IntrospectionResponse{Active: false}, nil
+}
+
+// revoke the access token or the refresh token
+// the tokens of an oidc client can only be revoked by the client, the tokens of this server only by the confidential clients
+// an invalid token is not an error, the client can not do anything about it
+func Revoke(request *requests.OIDCTokenRevokeRequest) *OAuthError {
+
+	client, oauthErr := authenticateClient(request.ClientId, request.ClientSecret)
+	if oauthErr != nil {
+		return oauthErr
+	}
+
+	if request.Token == "" {
+		return invalidRequest("token is required")
+	}
+
+	unauthorizedClient := newOAuthError(http.StatusBadRequest, "unauthorized_client", "the token was not issued to the client")
+
+	for _, tokenType := range lookupsByHint(request.TokenTypeHint) {
+		switch tokenType {
+		case "access_token":
+			claims := activeAccessTokenClaims(request.Token)
+			if claims == nil {
+				continue
+			}
+
+			clientID, _ := claims["client_id"].(string)
+			if clientID != client.ID.Hex() && (clientID != "" || client.Public) {
+				return unauthorizedClient
+			}
+
+			jti, _ := claims["jti"].(string)
+			exp, _ := claims["exp"].(float64)
+			if err := jwt.RevokeAccessToken(jti, int64(exp)); err != nil {
+				return newOAuthError(http.StatusServiceUnavailable, "temporarily_unavailable", "")
+			}
+			return nil
+
+		case "refresh_token":
+			refreshToken := activeRefreshToken(request.Token)
+			if refreshToken == nil {
+				continue
+			}
+
+			if client.Public {
+				return unauthorizedClient
+			}
+
+			// the whole login is revoked with its access tokens, the rotated tokens of the family are useless anyway
+			if err := repositories.DeleteRefreshTokenByFamilyID(refreshToken.FamilyID); err != nil {
+				return newOAuthError(http.StatusServiceUnavailable, "temporarily_unavailable", "")
+			}
+			if err := jwt.RevokeFamilyAccessTokens(refreshToken.FamilyID); err != nil {
+				return newOAuthError(http.StatusServiceUnavailable, "temporarily_unavailable", "")
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package oidc
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/jwkmanager"
+	"gin-auth-mongo/utils/jwt"
+)
+
+// OAuthError is returned to the clients in the oauth2 error format
+// https://datatracker.ietf.org/doc/html/rfc6749#section-5.2
+type OAuthError struct {
+	Status      int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(status int, code string, description string) *OAuthError {
+	return &OAuthError{Status: status, Code: code, Description: description}
+}
+
+func invalidRequest(description string) *OAuthError {
+	return newOAuthError(http.StatusBadRequest, "invalid_request", description)
+}
+
+// the descriptions shown on the consent screen
+var scopeDescriptions = map[string]string{
+	"openid":  "Sign you in with your account",
+	"profile": "View your username, nickname and avatar",
+	"email":   "View your email address",
+	"phone":   "View your phone number",
+}
+
+// split the space separated scopes and remove the duplicates
+func parseScopes(scope string) []string {
+	scopes := make([]string, 0)
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// append the query to the redirect uri, the existing query of the uri is kept
+func appendQuery(redirectURI string, query url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range query {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// the claims of the user released for the granted scopes, used by the id token and the userinfo endpoint
+func userClaims(user *models.User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{}
+
+	if slices.Contains(scopes, "profile") {
+		claims["name"] = user.Nickname
+		claims["preferred_username"] = user.Username
+		claims["picture"] = user.Avatar
+	}
+
+	if slices.Contains(scopes, "email") {
+		// the email is verified on registration
+		claims["email"] = user.Email
+		claims["email_verified"] = true
+	}
+
+	if slices.Contains(scopes, "phone") && user.Phone != "" {
+		claims["phone_number"] = user.Phone
+		claims["phone_number_verified"] = true
+	}
+
+	return claims
+}
+
+// the openid connect discovery document
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+func Discovery() map[string]interface{} {
+	issuer := jwt.OIDCIssuer()
+	endpoint := issuer + "/api/v1/oidc"
+
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                endpoint + "/authorize",
+		"token_endpoint":                        endpoint + "/token",
+		"userinfo_endpoint":                     endpoint + "/userinfo",
+		"introspection_endpoint":                issuer + "/api/v1/oauth/introspect",
+		"revocation_endpoint":                   issuer + "/api/v1/oauth/revoke",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": jwkmanager.PublishedAlgorithms(),
+		"scopes_supported":                      consts.OIDC_SUPPORTED_SCOPES,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "nonce",
+			"name", "preferred_username", "picture",
+			"email", "email_verified",
+			"phone_number", "phone_number_verified",
+		},
+	}
+}
+
+// the metadata of the access tokens issued by this server to its own frontend
+// resource servers verify them with the keys at jwks_uri and the issuer
+// https://datatracker.ietf.org/doc/html/rfc8414#section-2
+func IssuerMetadata() map[string]interface{} {
+	baseURL := jwt.OIDCIssuer()
+	endpoint := baseURL + "/api/v1/auth"
+
+	return map[string]interface{}{
+		"issuer":                            consts.JWT_ISSUER,
+		"jwks_uri":                          baseURL + "/.well-known/jwks.json",
+		"token_endpoint":                    endpoint + "/token/refresh",
+		"token_info_endpoint":               endpoint + "/token/info",
+		"client_credentials_token_endpoint": baseURL + "/api/v1/oauth/token",
+		"introspection_endpoint":            baseURL + "/api/v1/oauth/introspect",
+		"revocation_endpoint":               baseURL + "/api/v1/oauth/revoke",
+		"introspection_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"login_endpoints": map[string]string{
+			"email_password":    endpoint + "/login/email",
+			"username_password": endpoint + "/login/username",
+			"email_link":        endpoint + "/login/email/link",
+			"email_code":        endpoint + "/login/email/code",
+			"phone_code":        endpoint + "/login/phone/code",
+			"passkey":           endpoint + "/login/passkey/begin",
+			"two_factor":        endpoint + "/login/2fa/verify",
+		},
+		"token_signing_alg_values_supported": jwkmanager.PublishedAlgorithms(),
+		"access_token_expires_in":            consts.JWT_ACCESS_TOKEN_EXPIRY * 60,
+		"openid_configuration":               baseURL + "/.well-known/openid-configuration",
+	}
+}
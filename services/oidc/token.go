@@ -0,0 +1,159 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/jwt"
+)
+
+// TokenResponse is the successful response of the token endpoint
+// https://openid.net/specs/openid-connect-core-1_0.html#TokenResponse
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"` // unit: seconds
+	IDToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope"`
+}
+
+// get and delete the authorization code, it can only be used once
+func popAuthorizationCode(code string) (*authorizationCode, error) {
+	data, err := databases.RedisGet(consts.OIDC_AUTH_CODE + code)
+	if err != nil || data == "" {
+		return nil, err
+	}
+	databases.RedisDel(consts.OIDC_AUTH_CODE + code)
+
+	var authCode authorizationCode
+	if err := json.Unmarshal([]byte(data), &authCode); err != nil {
+		return nil, err
+	}
+	return &authCode, nil
+}
+
+// BASE64URL(SHA256(code_verifier)) MUST equal the code challenge
+func verifyCodeChallenge(codeChallenge string, codeVerifier string) bool {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(hash[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
+
+// exchange the authorization code for the access token and the id token
+func Token(request *requests.OIDCTokenRequest) (*TokenResponse, *OAuthError) {
+
+	// the service accounts can use the token endpoint of the discovery document too
+	if request.GrantType == "client_credentials" {
+		return ClientCredentialsToken(request)
+	}
+
+	if request.GrantType != "authorization_code" {
+		return nil, newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or client_credentials")
+	}
+
+	if request.Code == "" {
+		return nil, invalidRequest("code is required")
+	}
+
+	client, oauthErr := authenticateClient(request.ClientId, request.ClientSecret)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	invalidGrant := newOAuthError(http.StatusBadRequest, "invalid_grant", "invalid or expired authorization code")
+
+	authCode, err := popAuthorizationCode(request.Code)
+	if err != nil || authCode == nil {
+		return nil, invalidGrant
+	}
+
+	if authCode.ClientID != client.ID.Hex() || authCode.RedirectURI != request.RedirectUri {
+		return nil, invalidGrant
+	}
+
+	if authCode.CodeChallenge != "" {
+		if !verifyCodeChallenge(authCode.CodeChallenge, request.CodeVerifier) {
+			return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "invalid code_verifier")
+		}
+	} else if client.Public {
+		return nil, invalidGrant
+	}
+
+	user, err := repositories.GetUserByID(authCode.UserID)
+	if err != nil || user == nil || account.CheckUserStatus(user) != nil {
+		return nil, invalidGrant
+	}
+
+	issuedAt := time.Now()
+
+	accessToken, err := jwt.GenerateOIDCAccessToken(authCode.UserID, authCode.ClientID, authCode.Scopes, issuedAt)
+	if err != nil {
+		return nil, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+
+	idToken, err := jwt.GenerateIDToken(authCode.UserID, authCode.ClientID, authCode.Nonce, userClaims(user, authCode.Scopes), issuedAt)
+	if err != nil {
+		return nil, newOAuthError(http.StatusInternalServerError, "server_error", "")
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   consts.OIDC_ACCESS_TOKEN_EXPIRY * 60,
+		IDToken:     idToken,
+		Scope:       strings.Join(authCode.Scopes, " "),
+	}, nil
+}
+
+// return the claims of the user for the access token issued by the token endpoint
+func UserInfo(accessToken string) (map[string]interface{}, *OAuthError) {
+
+	invalidToken := newOAuthError(http.StatusUnauthorized, "invalid_token", "invalid or expired access token")
+
+	claims, err := jwt.ParseJWTClaims(accessToken)
+	if err != nil {
+		return nil, invalidToken
+	}
+
+	// only the access tokens issued to the oidc clients for the users are accepted
+	if _, ok := claims["client_id"].(string); !ok || claims["iss"] != jwt.OIDCIssuer() || claims["sub_type"] != consts.SUBJECT_TYPE_USER || claims["token_use"] == consts.TOKEN_USE_ID {
+		return nil, invalidToken
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Unix() > int64(exp) {
+		return nil, invalidToken
+	}
+
+	if revoked, err := jwt.IsAccessTokenRevoked(claims); err != nil || revoked {
+		return nil, invalidToken
+	}
+
+	scope, _ := claims["scope"].(string)
+	scopes := parseScopes(scope)
+	if !slices.Contains(scopes, "openid") {
+		return nil, newOAuthError(http.StatusForbidden, "insufficient_scope", "the openid scope is required")
+	}
+
+	userID, _ := claims["sub"].(string)
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, invalidToken
+	}
+
+	userInfo := userClaims(user, scopes)
+	userInfo["sub"] = userID
+
+	return userInfo, nil
+}
@@ -0,0 +1,148 @@
+package role
+
+import (
+	"errors"
+	"slices"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/jwt"
+	"gin-auth-mongo/utils/rbac"
+)
+
+func validatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		if !rbac.IsValidPermission(permission) {
+			return errors.New("unknown permission: " + permission)
+		}
+	}
+	return nil
+}
+
+// the permissions are in the access tokens, revoke the tokens of the users so they refresh with the new permissions
+func revokeRoleAccessTokens(name string) error {
+	userIDs, err := repositories.GetUserIDsByRole(name)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := jwt.RevokeUserAccessTokens(userID.Hex()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetRoles() ([]models.Role, error) {
+	roles, err := repositories.GetRoles()
+	if err != nil {
+		return nil, errors.New("get roles failed")
+	}
+	if roles == nil {
+		roles = []models.Role{}
+	}
+	return roles, nil
+}
+
+func CreateRole(request *requests.CreateRoleRequest) (*models.Role, error) {
+
+	if err := validatePermissions(request.Permissions); err != nil {
+		return nil, err
+	}
+
+	existing, err := repositories.GetRoleByName(request.Name)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+	if existing != nil {
+		return nil, errors.New("role already exists")
+	}
+
+	role := &models.Role{
+		Name:        request.Name,
+		Description: request.Description,
+		Permissions: request.Permissions,
+	}
+
+	err = repositories.CreateRole(role)
+	if err != nil {
+		return nil, errors.New("create role failed")
+	}
+
+	return role, nil
+}
+
+// update the description and the permissions, the users of the role get the new permissions on the next refresh
+func UpdateRole(request *requests.UpdateRoleRequest) (*models.Role, error) {
+
+	if err := validatePermissions(request.Permissions); err != nil {
+		return nil, err
+	}
+
+	found, err := repositories.UpdateRoleByName(request.Name, request.Description, request.Permissions)
+	if err != nil || !found {
+		return nil, errors.New("role not found")
+	}
+
+	if err := revokeRoleAccessTokens(request.Name); err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	role, err := repositories.GetRoleByName(request.Name)
+	if err != nil || role == nil {
+		return nil, errors.New("role not found")
+	}
+
+	return role, nil
+}
+
+// delete the role and remove it from the users
+func DeleteRole(name string) error {
+
+	userIDs, err := repositories.GetUserIDsByRole(name)
+	if err != nil {
+		return errors.New("try again later")
+	}
+
+	deleted, err := repositories.DeleteRoleByName(name)
+	if err != nil || !deleted {
+		return errors.New("role not found")
+	}
+
+	if err := repositories.RemoveRoleFromUsers(name); err != nil {
+		return errors.New("try again later")
+	}
+
+	for _, userID := range userIDs {
+		jwt.RevokeUserAccessTokens(userID.Hex())
+	}
+
+	return nil
+}
+
+// replace the roles of the user, the user gets the new permissions on the next refresh
+func SetUserRoles(request *requests.SetUserRolesRequest) error {
+
+	roles := slices.Clone(request.Roles)
+	if roles == nil {
+		roles = []string{}
+	}
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+
+	existing, err := repositories.GetRolesByNames(roles)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if len(existing) != len(roles) {
+		return errors.New("unknown role")
+	}
+
+	found, err := repositories.UpdateUserRolesByID(request.UserId, roles)
+	if err != nil || !found {
+		return errors.New("user not found")
+	}
+
+	return jwt.RevokeUserAccessTokens(request.UserId)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"gin-auth-mongo/databases"
 	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/jwt"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -22,6 +23,36 @@ func DeleteUserAccount(userID string) error {
 			return nil, err
 		}
 
+		// delete all the passkeys from the database
+		err = repositories.DeletePasskeyCredentialByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// delete all the personal access tokens from the database
+		err = repositories.DeletePersonalAccessTokensByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// delete all the linked oauth identities from the database
+		err = repositories.DeleteUserIdentitiesByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// delete all the consents granted to the oidc clients from the database
+		err = repositories.DeleteOAuthConsentsByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// delete all the security events from the database
+		err = repositories.DeleteSecurityEventsByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
 		// delete the user from the database
 		err = repositories.DeleteUserByID(userID)
 		if err != nil {
@@ -34,5 +65,7 @@ func DeleteUserAccount(userID string) error {
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// the access tokens of the deleted user MUST NOT be used anymore
+	return jwt.RevokeUserAccessTokens(userID)
 }
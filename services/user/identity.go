@@ -0,0 +1,41 @@
+package user
+
+import (
+	"errors"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/repositories"
+	authService "gin-auth-mongo/services/auth"
+)
+
+func GetUserIdentities(userID string) ([]models.UserIdentity, error) {
+	identities, err := repositories.GetUserIdentitiesByUserID(userID)
+	if err != nil {
+		return nil, errors.New("get linked accounts failed")
+	}
+	if identities == nil {
+		identities = []models.UserIdentity{}
+	}
+	return identities, nil
+}
+
+// return the authorization url of the provider, the account is linked in the oauth callback
+func UserLinkIdentityBegin(userID string, provider string) (string, error) {
+	return authService.CreateOAuthAuthorizationURL(provider, userID)
+}
+
+// the user can still sign in with the password, the email link or code after unlinking
+func UserUnlinkIdentity(userID string, provider string) error {
+
+	identity, err := repositories.GetUserIdentityByProviderAndUserID(provider, userID)
+	if err != nil || identity == nil {
+		return errors.New("the " + provider + " account is not linked")
+	}
+
+	err = repositories.DeleteUserIdentityByProviderAndUserID(provider, userID)
+	if err != nil {
+		return errors.New("unlink " + provider + " account failed")
+	}
+
+	return nil
+}
@@ -3,12 +3,31 @@ package user
 import (
 	"gin-auth-mongo/models/requests"
 	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/jwt"
 )
 
-func UserLogoutCurrentDevice(userID string, request *requests.LogoutRequest) error {
-	return repositories.DeleteRefreshTokenByUserIDAndDevice(userID, request.Device)
+// delete the refresh tokens of the current session and revoke all its access tokens, the same as RevokeUserSession
+// the access tokens issued before the sessions had families carry no fid, the refresh tokens of the device are deleted instead
+func UserLogoutCurrentDevice(userID string, jti string, familyID string, expiredAtUnix int64, request *requests.LogoutRequest) error {
+	if familyID == "" {
+		err := repositories.DeleteRefreshTokenByUserIDAndDevice(userID, request.Device)
+		if err != nil {
+			return err
+		}
+		return jwt.RevokeAccessToken(jti, expiredAtUnix)
+	}
+
+	if _, err := repositories.DeleteRefreshTokenByUserIDAndFamilyID(userID, familyID); err != nil {
+		return err
+	}
+	return jwt.RevokeFamilyAccessTokens(familyID)
 }
 
+// delete all the refresh tokens and revoke all the access tokens issued until now
 func UserLogoutAllDevice(userID string) error {
-	return repositories.DeleteRefreshTokenByUserID(userID)
+	err := repositories.DeleteRefreshTokenByUserID(userID)
+	if err != nil {
+		return err
+	}
+	return jwt.RevokeUserAccessTokens(userID)
 }
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/passkey"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// load the user with the passkeys for the webauthn ceremonies
+func getPasskeyUser(userID string) (*passkey.User, error) {
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	credentials, err := repositories.GetPasskeyCredentialsByUserID(userID)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	return &passkey.User{User: user, Credentials: credentials}, nil
+}
+
+// begin the registration ceremony, return the options for navigator.credentials.create()
+func UserPasskeyRegisterBegin(userID string) (*protocol.CredentialCreation, error) {
+
+	passkeyUser, err := getPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the same authenticator can not be registered twice
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(passkeyUser.Credentials))
+	for _, c := range passkeyUser.WebAuthnCredentials() {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	creation, session, err := passkey.WebAuthn.BeginRegistration(
+		passkeyUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	err = passkey.SaveSession(consts.PASSKEY_REGISTER_SESSION+userID, session)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	return creation, nil
+}
+
+// finish the registration ceremony and store the new passkey
+func UserPasskeyRegisterFinish(userID string, request *requests.PasskeyRegisterFinishRequest) (*models.UserPasskeyCredential, error) {
+
+	session, err := passkey.PopSession(consts.PASSKEY_REGISTER_SESSION + userID)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeyUser, err := getPasskeyUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedResponse, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(request.Credential))
+	if err != nil {
+		return nil, errors.New("invalid passkey credential")
+	}
+
+	credential, err := passkey.WebAuthn.CreateCredential(passkeyUser, *session, parsedResponse)
+	if err != nil {
+		return nil, errors.New("invalid passkey credential")
+	}
+
+	name := request.Name
+	if name == "" {
+		name = "passkey"
+	}
+
+	passkeyCredential := passkey.FromWebAuthnCredential(passkeyUser.User.ID, name, credential)
+	err = repositories.CreatePasskeyCredential(passkeyCredential)
+	if err != nil {
+		return nil, errors.New("register passkey failed")
+	}
+
+	return passkeyCredential, nil
+}
+
+func GetUserPasskeys(userID string) ([]models.UserPasskeyCredential, error) {
+	credentials, err := repositories.GetPasskeyCredentialsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		credentials = []models.UserPasskeyCredential{}
+	}
+	return credentials, nil
+}
+
+func DeleteUserPasskey(userID string, id string) error {
+	return repositories.DeletePasskeyCredentialByIDAndUserID(id, userID)
+}
@@ -0,0 +1,91 @@
+package user
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/jwt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreatedPersonalAccessToken is returned once on creation, the token can not be retrieved later
+type CreatedPersonalAccessToken struct {
+	*models.UserPersonalAccessToken
+	Token string `json:"token"`
+}
+
+// create a personal access token with the scopes, eg: for the scripts and the ci
+func CreatePersonalAccessToken(userID string, request *requests.CreatePersonalAccessTokenRequest) (*CreatedPersonalAccessToken, error) {
+
+	userIDObject, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	for _, scope := range request.Scopes {
+		if !slices.Contains(consts.PERSONAL_ACCESS_TOKEN_SCOPES, scope) {
+			return nil, errors.New("unsupported scope: " + scope)
+		}
+	}
+
+	count, err := repositories.CountPersonalAccessTokensByUserID(userID)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+	if count >= consts.PERSONAL_ACCESS_TOKEN_MAX_PER_USER {
+		return nil, errors.New("too many personal access tokens")
+	}
+
+	token, err := jwt.GeneratePersonalAccessToken()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	scopes := slices.Clone(request.Scopes)
+	slices.Sort(scopes)
+
+	personalAccessToken := &models.UserPersonalAccessToken{
+		UserID:    userIDObject,
+		Name:      request.Name,
+		TokenHash: crypto.HashToken(token),
+		TokenHint: token[:len(consts.PERSONAL_ACCESS_TOKEN_PREFIX)+4],
+		Scopes:    slices.Compact(scopes),
+	}
+	if request.ExpiresInDays > 0 {
+		personalAccessToken.ExpiredAt = time.Now().AddDate(0, 0, request.ExpiresInDays).Format(consts.DATETIME_NANO_FORMAT)
+	}
+
+	err = repositories.CreatePersonalAccessToken(personalAccessToken)
+	if err != nil {
+		return nil, errors.New("create personal access token failed")
+	}
+
+	return &CreatedPersonalAccessToken{UserPersonalAccessToken: personalAccessToken, Token: token}, nil
+}
+
+func GetPersonalAccessTokens(userID string) ([]models.UserPersonalAccessToken, error) {
+	tokens, err := repositories.GetPersonalAccessTokensByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []models.UserPersonalAccessToken{}
+	}
+	return tokens, nil
+}
+
+// revoke the personal access token, it is rejected from the next request
+func DeletePersonalAccessToken(userID string, id string) error {
+	deleted, err := repositories.DeletePersonalAccessTokenByIDAndUserID(id, userID)
+	if err != nil || !deleted {
+		return errors.New("personal access token not found")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"errors"
+
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	authService "gin-auth-mongo/services/auth"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/mail"
+)
+
+// the pending code is bound to both the user and the phone number
+func phoneBindCodeKey(userID string, phone string) string {
+	return consts.VERIFY_PHONE_BIND_CODE + userID + ":" + phone
+}
+
+// send a code to the new phone number, the phone is saved after the code is verified
+func UserUpdatePhone(userID string, request *requests.UpdatePhoneRequest) error {
+
+	// the cooldown starts before the phone is looked up, a phone in use is throttled the same way
+	if err := authService.StartVerificationCodeCooldown(mail.VerificationMethodPhone, phoneBindCodeKey(userID, request.Phone)); err != nil {
+		return err
+	}
+
+	existingUser, err := repositories.GetUserByPhone(request.Phone)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if existingUser != nil {
+		if existingUser.ID.Hex() == userID {
+			return errors.New("phone is already bound to your account")
+		}
+		return errors.New("phone is already in use")
+	}
+
+	return authService.SendPhoneVerificationCode(phoneBindCodeKey(userID, request.Phone), request.Phone, mail.VerificationRequestTypeBindPhone)
+}
+
+func UserVerifyPhone(userID string, request *requests.PhoneVerifyRequest) error {
+
+	err := authService.VerifyPhoneVerificationCode(phoneBindCodeKey(userID, request.Phone), request.Code)
+	if err != nil {
+		return err
+	}
+
+	// the phone may have been taken while the code was pending
+	existingUser, err := repositories.GetUserByPhone(request.Phone)
+	if err != nil {
+		return errors.New("try again later")
+	}
+	if existingUser != nil && existingUser.ID.Hex() != userID {
+		return errors.New("phone is already in use")
+	}
+
+	err = repositories.UpdateUserPhoneByID(userID, request.Phone)
+	if err != nil {
+		return errors.New("update phone failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"errors"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/jwt"
+	"time"
+)
+
+// get the logins of the user, the session id is the refresh token family
+// the session of the access token in use is marked as current
+func GetUserSessions(userID string, currentFamilyID string) ([]*model.Session, error) {
+	refreshTokens, err := repositories.GetActiveRefreshTokensByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(refreshTokens))
+	for _, refreshToken := range refreshTokens {
+		expiredAt, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, refreshToken.ExpiredAt, time.Local)
+		if err != nil || time.Now().After(expiredAt) {
+			continue
+		}
+
+		sessions = append(sessions, &model.Session{
+			ID:         refreshToken.FamilyID,
+			Device:     refreshToken.Device,
+			IP:         refreshToken.IP,
+			UserAgent:  refreshToken.UserAgent,
+			CreatedAt:  refreshToken.CreatedAt,
+			LastUsedAt: refreshToken.LastUsedAt,
+			ExpiredAt:  refreshToken.ExpiredAt,
+			Current:    refreshToken.FamilyID == currentFamilyID,
+		})
+	}
+
+	return sessions, nil
+}
+
+// sign out the session, its refresh tokens are deleted and its access tokens revoked
+func RevokeUserSession(userID string, sessionID string) error {
+	deleted, err := repositories.DeleteRefreshTokenByUserIDAndFamilyID(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("session not found")
+	}
+	return jwt.RevokeFamilyAccessTokens(sessionID)
+}
@@ -0,0 +1,140 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/graph/model"
+	"gin-auth-mongo/models/requests"
+	"gin-auth-mongo/repositories"
+	authService "gin-auth-mongo/services/auth"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+	"gin-auth-mongo/utils/datetime"
+	"gin-auth-mongo/utils/mail"
+	"gin-auth-mongo/utils/mfa"
+)
+
+// generate a new totp secret, it is kept in redis until the user confirms it with a code
+func UserTotpSetup(userID string) (*model.TotpSetup, error) {
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two factor authentication is already enabled")
+	}
+
+	secret, uri, err := mfa.GenerateTOTPSecret(user.Email)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	encryptedSecret, err := mfa.EncryptSecret(secret)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	err = databases.RedisSet(consts.MFA_TOTP_SETUP_SECRET+userID, encryptedSecret, consts.MFA_TOTP_SETUP_EXPIRY, datetime.MINUTES)
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	expiredAt := time.Now().Add(time.Duration(consts.MFA_TOTP_SETUP_EXPIRY) * time.Minute).Format(consts.DATETIME_NANO_FORMAT)
+
+	return &model.TotpSetup{
+		Secret:     secret,
+		OtpauthURI: uri,
+		ExpiredAt:  expiredAt,
+	}, nil
+}
+
+// confirm the pending totp secret and enable two factor authentication
+// the recovery codes are returned only once
+func UserTotpConfirm(userID string, request *requests.TotpConfirmRequest) (*model.RecoveryCodes, error) {
+
+	encryptedSecret, err := databases.RedisGet(consts.MFA_TOTP_SETUP_SECRET + userID)
+	if err != nil || encryptedSecret == "" {
+		return nil, errors.New("please setup two factor authentication again")
+	}
+
+	if !mfa.ValidateEncryptedTOTPCode(encryptedSecret, request.Code) {
+		return nil, errors.New("invalid code")
+	}
+
+	codes, hashedCodes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	err = repositories.UpdateUserTwoFactorByID(userID, true, encryptedSecret, hashedCodes)
+	if err != nil {
+		return nil, errors.New("enable two factor authentication failed")
+	}
+
+	databases.RedisDel(consts.MFA_TOTP_SETUP_SECRET + userID)
+
+	return &model.RecoveryCodes{Codes: codes}, nil
+}
+
+// disable two factor authentication, both the password and a current code are required
+func UserTotpDisable(userID string, request *requests.TotpDisableRequest) error {
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+
+	if !user.TwoFactorEnabled {
+		return errors.New("two factor authentication is not enabled")
+	}
+
+	match, err := crypto.VerifyPassword(request.Password, user.Password)
+	if err != nil || !match {
+		return errors.New("incorrect password")
+	}
+
+	if !authService.VerifySecondFactor(user, request.Code, mail.SecondFactorPurposeDisableTwoFactor) {
+		return errors.New("invalid code")
+	}
+
+	err = repositories.UpdateUserTwoFactorByID(userID, false, "", []string{})
+	if err != nil {
+		return errors.New("disable two factor authentication failed")
+	}
+
+	return nil
+}
+
+// replace all the recovery codes, the old ones can no longer be used
+func UserRegenerateRecoveryCodes(userID string, request *requests.RegenerateRecoveryCodesRequest) (*model.RecoveryCodes, error) {
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, errors.New("two factor authentication is not enabled")
+	}
+
+	match, err := crypto.VerifyPassword(request.Password, user.Password)
+	if err != nil || !match {
+		return nil, errors.New("incorrect password")
+	}
+
+	codes, hashedCodes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, errors.New("try again later")
+	}
+
+	err = repositories.UpdateUserRecoveryCodesByID(userID, hashedCodes)
+	if err != nil {
+		return nil, errors.New("regenerate recovery codes failed")
+	}
+
+	return &model.RecoveryCodes{Codes: codes}, nil
+}
@@ -0,0 +1,54 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+)
+
+// check if the user can login and use the api, see consts.USER_STATUS_*
+// an expired suspension is not enforced even if the cron has not lifted it yet
+func CheckUserStatus(user *models.User) error {
+	switch user.Status {
+	case consts.USER_STATUS_DISABLED:
+		return errors.New("account is disabled")
+	case consts.USER_STATUS_BANNED:
+		return errors.New("account is banned")
+	case consts.USER_STATUS_SUSPENDED:
+		until, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, user.StatusUntil, time.Local)
+		if err != nil || time.Now().Before(until) {
+			return errors.New("account is suspended until " + user.StatusUntil)
+		}
+	}
+	return nil
+}
+
+// mark the user as restricted so the access tokens issued before are rejected at once
+// the mark is kept until the suspension ends, or until the last access token expires as no token can be issued meanwhile
+func RestrictUser(userID string, status string, until string) error {
+	expiry := consts.JWT_ACCESS_TOKEN_EXPIRY * 60
+	if status == consts.USER_STATUS_SUSPENDED {
+		untilTime, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, until, time.Local)
+		if err != nil {
+			return err
+		}
+		expiry = min(expiry, int(time.Until(untilTime).Seconds()))
+	}
+	if expiry <= 0 {
+		return nil
+	}
+	return databases.RedisSet(consts.USER_STATUS_RESTRICTED+userID, status, expiry, datetime.SECONDS)
+}
+
+func LiftUserRestriction(userID string) error {
+	return databases.RedisDel(consts.USER_STATUS_RESTRICTED + userID)
+}
+
+// get the status of a restricted user, empty if the user is not restricted
+func GetUserRestriction(userID string) (string, error) {
+	return databases.RedisGet(consts.USER_STATUS_RESTRICTED + userID)
+}
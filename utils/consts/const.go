@@ -4,8 +4,63 @@ const MONGO_DATABASE = "mongo_test"
 
 // jwt related
 const JWT_ISSUER = "gin-auth-mongo"
-const JWT_ACCESS_TOKEN_EXPIRY = 60 * 24 * 14 // unit: minutes
-const JWT_REFRESH_TOKEN_EXPIRY = 90          // unit: days
+const JWT_ACCESS_TOKEN_EXPIRY = 60 * 24 * 14       // unit: minutes
+const JWT_REFRESH_TOKEN_EXPIRY = 90                // unit: days
+const JWT_DENYLIST = "jwt:denylist:"               // revoked access tokens by jti
+const JWT_USER_WATERMARK = "jwt:user:watermark:"   // the access tokens of the user issued until the watermark are revoked
+const JWT_FAMILY_DENYLIST = "jwt:family:denylist:" // revoked sessions by refresh token family
+
+// a rotated refresh token is kept to detect its reuse, it is removed after the retention
+const JWT_ROTATED_REFRESH_TOKEN_RETENTION = 30 // unit: days
+
+// the sub_type claim tells what the subject of an access token is
+const SUBJECT_TYPE_USER = "user"
+const SUBJECT_TYPE_SERVICE_ACCOUNT = "service_account"
+
+// the token_use claim of the id tokens, they tell who the user is and are never accepted as access tokens
+const TOKEN_USE_ID = "id"
+
+// service accounts
+const SERVICE_ACCOUNT_ACCESS_TOKEN_EXPIRY = 60 // unit: minutes
+
+// role based access control, a permission is <resource>:<action>, "<resource>:*" grants every action on the resource
+const ROLE_ADMIN = "admin"
+const PERMISSION_ALL = "*"
+const PERMISSION_USERS_READ = "users:read"
+const PERMISSION_USERS_WRITE = "users:write"
+const PERMISSION_ROLES_READ = "roles:read"
+const PERMISSION_ROLES_WRITE = "roles:write"
+
+var PERMISSIONS = []string{PERMISSION_USERS_READ, PERMISSION_USERS_WRITE, PERMISSION_ROLES_READ, PERMISSION_ROLES_WRITE}
+
+// user status, a user who is not active can not login and its sessions are revoked
+// a suspension is lifted when it expires, a ban is permanent
+const USER_STATUS_ACTIVE = "active"
+const USER_STATUS_DISABLED = "disabled"
+const USER_STATUS_SUSPENDED = "suspended"
+const USER_STATUS_BANNED = "banned"
+const USER_STATUS_RESTRICTED = "user:status:restricted:" // the users who are not active, the access tokens issued before are rejected at once
+
+// admin user listing
+const ADMIN_USER_PAGE_SIZE = 20
+const ADMIN_USER_MAX_PAGE_SIZE = 100
+
+// security events
+const SECURITY_EVENT_REFRESH_TOKEN_REUSE = "refresh_token_reuse"
+
+// openid connect provider
+const OIDC_ACCESS_TOKEN_EXPIRY = 60 // unit: minutes
+const OIDC_ID_TOKEN_EXPIRY = 60     // unit: minutes
+const OIDC_AUTHORIZE_REQUEST = "oidc:authorize:request:"
+const OIDC_AUTHORIZE_REQUEST_EXPIRY = 10 // unit: minutes
+const OIDC_AUTH_CODE = "oidc:auth:code:"
+const OIDC_AUTH_CODE_EXPIRY = 5 // unit: minutes
+
+var OIDC_SUPPORTED_SCOPES = []string{"openid", "profile", "email", "phone"}
+
+// well-known documents
+const JWKS_CACHE_MAX_AGE = 300        // unit: seconds // keep it short so the rotated keys are picked up soon
+const WELL_KNOWN_CACHE_MAX_AGE = 3600 // unit: seconds
 
 // email register and reset password
 const VERIFY_EMAIL_REGISTER_FLOW_ID = "verify:email:register:flow_id:"
@@ -16,11 +71,79 @@ const VERIFY_EMAIL_REGISTER_LINK_EXPIRY = 120 // unit: minutes
 const VERIFY_EMAIL_REGISTER_CODE_EXPIRY = 15  // unit: minutes
 
 const VERIFY_EMAIL_RESET_PWD_FLOW_ID = "verify:email:resetpwd:flow_id:"
+const VERIFY_EMAIL_RESET_PWD_LATEST_FLOW_ID = "verify:email:resetpwd:latest_flow_id:" // only the latest link of the user is valid
 const VERIFY_EMAIL_RESET_PWD_CODE = "verify:email:resetpwd:code:"
 const VERIFY_EMAIL_RESET_PWD_PASSWORD = "verify:email:resetpwd:password:"
 const VERIFY_EMAIL_RESET_PWD_LINK_EXPIRY = 120 // unit: minutes
 const VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY = 15  // unit: minutes
 
+const VERIFY_EMAIL_LOGIN_FLOW_ID = "verify:email:login:flow_id:"
+const VERIFY_EMAIL_LOGIN_CODE = "verify:email:login:code:"
+const VERIFY_EMAIL_LOGIN_LINK_EXPIRY = 15 // unit: minutes
+const VERIFY_EMAIL_LOGIN_CODE_EXPIRY = 10 // unit: minutes
+
+// the six-digit email codes are burned after too many wrong attempts, a new code can be sent after the cooldown
+const VERIFY_EMAIL_CODE_ATTEMPTS = "verify:email:attempts:"
+const VERIFY_EMAIL_CODE_COOLDOWN = "verify:email:cooldown:"
+const VERIFY_EMAIL_CODE_MAX_ATTEMPTS = 5
+const VERIFY_EMAIL_CODE_COOLDOWN_EXPIRY = 60 // unit: seconds
+
+// failed password logins per account, the attempts against an unknown email or username are counted the same way
+// after LOGIN_DELAY_THRESHOLD failures the next attempt waits 1, 2, 4... seconds, after LOGIN_LOCKOUT_THRESHOLD failures the account is locked
+const LOGIN_FAILED_ATTEMPTS = "login:failed:attempts:"
+const LOGIN_FAILED_DELAY = "login:failed:delay:"
+const LOGIN_LOCKOUT = "login:lockout:"
+const LOGIN_UNLOCK_FLOW_ID = "login:unlock:flow_id:"
+const LOGIN_FAILED_WINDOW = 15     // unit: minutes // the failures are forgotten after a quiet window
+const LOGIN_DELAY_THRESHOLD = 3    // start delaying after this many failures
+const LOGIN_MAX_DELAY = 30         // unit: seconds
+const LOGIN_LOCKOUT_THRESHOLD = 10 // lock the account after this many failures
+const LOGIN_LOCKOUT_EXPIRY = 30    // unit: minutes // the unlock link expires with the lockout
+
+// phone verification
+const VERIFY_PHONE_BIND_CODE = "verify:phone:bind:code:"
+const VERIFY_PHONE_LOGIN_CODE = "verify:phone:login:code:"
+const VERIFY_PHONE_CODE_ATTEMPTS = "verify:phone:attempts:"
+const VERIFY_PHONE_CODE_EXPIRY = 10      // unit: minutes
+const VERIFY_PHONE_CODE_MAX_ATTEMPTS = 5 // the code is burned after too many wrong attempts
+const VERIFY_PHONE_CODE_COOLDOWN = "verify:phone:cooldown:"
+const VERIFY_PHONE_CODE_COOLDOWN_EXPIRY = 60 // unit: seconds // a new code can be sent after the cooldown
+
+// oauth social login
+const OAUTH_STATE = "oauth:state:"
+const OAUTH_STATE_EXPIRY = 10 // unit: minutes
+const OAUTH_LOGIN_FLOW_ID = "oauth:login:flow_id:"
+const OAUTH_LOGIN_FLOW_EXPIRY = 5 // unit: minutes
+
+// two factor authentication
+const MFA_TOTP_ISSUER = "gin-auth-mongo"
+const MFA_TOTP_PERIOD = 30 // unit: seconds
+const MFA_TOTP_SETUP_SECRET = "mfa:totp:setup:"
+const MFA_TOTP_SETUP_EXPIRY = 10 // unit: minutes
+const MFA_CHALLENGE_TOKEN = "mfa:challenge:token:"
+const MFA_CHALLENGE_ATTEMPTS = "mfa:challenge:attempts:"
+const MFA_CHALLENGE_EXPIRY = 5       // unit: minutes
+const MFA_CHALLENGE_MAX_ATTEMPTS = 5 // burn the challenge after too many wrong codes
+const MFA_RECOVERY_CODE_COUNT = 10
+
+// the wrong codes of the user across the challenges, too many lock the account like the failed logins
+const MFA_FAILED_ATTEMPTS = "mfa:failed:attempts:"
+
+// a totp code is accepted once, the steps up to the last accepted one are rejected
+const MFA_TOTP_LAST_STEP = "mfa:totp:last_step:"
+const MFA_TOTP_USED_STEP = "mfa:totp:used_step:"
+
+// passkey
+const PASSKEY_REGISTER_SESSION = "passkey:register:session:"
+const PASSKEY_LOGIN_SESSION = "passkey:login:session:"
+const PASSKEY_SESSION_EXPIRY = 5 // unit: minutes
+
+// personal access tokens
+const PERSONAL_ACCESS_TOKEN_PREFIX = "gam_pat_" // tells the personal access tokens from the jwts
+const PERSONAL_ACCESS_TOKEN_MAX_PER_USER = 20
+const PERSONAL_ACCESS_TOKEN_LAST_USED_INTERVAL = 60 // unit: seconds // the last used time is not updated more often
+var PERSONAL_ACCESS_TOKEN_SCOPES = []string{"user:read", "user:write", "oidc:read", "oidc:write"}
+
 // date and time format
 const DATE_FORMAT = "2006-01-02"
 const DATETIME_FORMAT = "2006-01-02 15:04:05"
@@ -41,6 +164,13 @@ const MAX_IMAGE_FILE_SIZE = 250 * 1024 * 1024 // 250MB
 // jwk related
 const PRIVATE_KEYS_FILE = ".private/keys.json"
 const PUBLIC_KEYS_FILE = ".public/keys.json"
+const KEY_STATES_FILE = ".private/key_states.json"
+const JWK_ROTATION_KEY_COUNT = 5             // new keys per rotation
+const JWK_PENDING_PERIOD = 60                // unit: minutes // MUST be longer than JWKS_CACHE_MAX_AGE
+const JWK_RSA_KEY_SIZE = 2048                // unit: bits // RS256 keys
+const JWK_KEY_SET_COLLECTION = "jwk_key_set" // mongo key store
+const JWK_KEY_SET_KEY = "jwk:keyset"         // redis key store
+const JWK_CHANGE_CHANNEL = "jwk:changed"     // notify the other instances to reload the keys
 
 // user related
 const DEFAULT_AVATAR = MINIO_PUBLIC_BUCKET_NAME + "/avatars/default.svg"
@@ -48,6 +178,11 @@ const DEFAULT_COVER_IMAGE = MINIO_PUBLIC_BUCKET_NAME + "/cover_images/default.sv
 
 const FRONTEND_REGISTER_ROUTE = "/auth/sign-up/complete"
 const FRONTEND_RESET_PASSWORD_ROUTE = "/auth/reset-password/complete"
+const FRONTEND_LOGIN_ROUTE = "/auth/sign-in/complete"
+const FRONTEND_UNLOCK_ACCOUNT_ROUTE = "/auth/unlock"
+const FRONTEND_OAUTH_LOGIN_ROUTE = "/auth/oauth/complete"
+const FRONTEND_OAUTH_LINK_ROUTE = "/settings/identities"
+const FRONTEND_OIDC_CONSENT_ROUTE = "/oauth/consent"
 
 var TRIP_PLAN_USER_PERMISSION_TYPE = []string{"view", "edit", "admin"}
 
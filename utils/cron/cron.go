@@ -1,6 +1,8 @@
 package cron
 
 import (
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
 	"gin-auth-mongo/utils/jwkmanager"
 	"log"
 	"time"
@@ -13,11 +15,64 @@ func StartCron() {
 	option := cron.WithLocation(loc)
 	c := cron.New(option)
 
-	// every sunday
+	// every sunday, the new keys are pending until AdvanceKeys activates them
 	_, err := c.AddFunc("0 0 * * 0", func() {
-		log.Println("Updating keys")
-		jwkmanager.UpdateKeys()
-		log.Println("Keys updated")
+		log.Println("Rotating keys")
+		if err := jwkmanager.RotateKeys(false, ""); err != nil {
+			log.Printf("Error rotating keys: %v", err)
+			return
+		}
+		log.Println("Keys rotated")
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	// every 5 minutes, activate the pending keys and remove the retired keys older than the refresh tokens
+	// the keys of a shared key store are synced first in case a change notification was missed
+	_, err = c.AddFunc("*/5 * * * *", func() {
+		if err := jwkmanager.SyncKeys(); err != nil {
+			log.Printf("Error syncing keys: %v", err)
+		}
+		if err := jwkmanager.AdvanceKeys(); err != nil {
+			log.Printf("Error advancing keys: %v", err)
+		}
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	// every 5 minutes, lift the suspensions which have ended
+	// the suspended users can login once the suspension ends even if it is not lifted yet
+	_, err = c.AddFunc("*/5 * * * *", func() {
+		lifted, err := repositories.LiftExpiredUserSuspensions()
+		if err != nil {
+			log.Printf("Error lifting suspensions: %v", err)
+			return
+		}
+		if lifted > 0 {
+			log.Printf("Lifted %d suspensions", lifted)
+		}
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	// every hour, remove the expired refresh tokens and the rotated ones after the retention
+	// every refresh adds a token to the family, the rotated tokens are only kept to detect their reuse
+	_, err = c.AddFunc("0 * * * *", func() {
+		rotatedBefore := time.Now().AddDate(0, 0, -consts.JWT_ROTATED_REFRESH_TOKEN_RETENTION)
+		deleted, err := repositories.DeleteExpiredRefreshTokens(rotatedBefore)
+		if err != nil {
+			log.Printf("Error deleting expired refresh tokens: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d expired refresh tokens", deleted)
+		}
 	})
 
 	if err != nil {
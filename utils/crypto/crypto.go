@@ -1,9 +1,13 @@
 package crypto
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -117,3 +121,61 @@ func generateRandomBytes(n uint32) ([]byte, error) {
 
 	return b, nil
 }
+
+// sha256 hash of a random token, eg: refresh token
+// the tokens are random with enough entropy, a fast hash without salt is enough to look them up
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// aes-gcm encrypt, the key MUST be 16, 24 or 32 bytes
+func EncryptString(plainText string, key string) (string, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", errors.New("failed to create AES cipher")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", errors.New("failed to create GCM")
+	}
+
+	nonce, err := generateRandomBytes(uint32(gcm.NonceSize()))
+	if err != nil {
+		return "", errors.New("failed to generate nonce")
+	}
+
+	cipherText := gcm.Seal(nonce, nonce, []byte(plainText), nil)
+	return base64.RawURLEncoding.EncodeToString(cipherText), nil
+}
+
+// aes-gcm decrypt, reverse of EncryptString
+func DecryptString(encrypted string, key string) (string, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", errors.New("failed to create AES cipher")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.New("failed to decode data by base64")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", errors.New("failed to create GCM")
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("failed to decrypt data")
+	}
+
+	nonce, cipherText := data[:nonceSize], data[nonceSize:]
+	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt data")
+	}
+	return string(plainText), nil
+}
@@ -0,0 +1,114 @@
+package jwkmanager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"slices"
+
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/square/go-jose/v3"
+)
+
+// the signing algorithms of the keys, the algorithm is a property of each key
+// a key set can mix algorithms, eg: while migrating from EdDSA to RS256 the keys of both are published
+var SupportedAlgorithms = []jose.SignatureAlgorithm{jose.EdDSA, jose.ES256, jose.RS256}
+
+// the algorithm of the new keys, set by the env JWK_SIGNING_ALGORITHM (default EdDSA)
+func DefaultAlgorithm() (jose.SignatureAlgorithm, error) {
+	algorithm := jose.SignatureAlgorithm(os.Getenv("JWK_SIGNING_ALGORITHM"))
+	if algorithm == "" {
+		return jose.EdDSA, nil
+	}
+	if !slices.Contains(SupportedAlgorithms, algorithm) {
+		return "", errors.New("unsupported signing algorithm: " + string(algorithm))
+	}
+	return algorithm, nil
+}
+
+// get the signing algorithm of the key
+// the keys generated before the algorithm was configurable are ed25519 keys with the alg Ed25519
+func KeyAlgorithm(key jose.JSONWebKey) jose.SignatureAlgorithm {
+	if key.Algorithm == "Ed25519" {
+		return jose.EdDSA
+	}
+	return jose.SignatureAlgorithm(key.Algorithm)
+}
+
+// replace the legacy alg Ed25519 with EdDSA, the alg registered for ed25519 keys
+func normalizeAlgorithms(keys []jose.JSONWebKey) {
+	for i := range keys {
+		keys[i].Algorithm = string(KeyAlgorithm(keys[i]))
+	}
+}
+
+// get the algorithms of the published keys, used in the discovery documents
+func PublishedAlgorithms() []string {
+	keyCacheLock.RLock()
+	defer keyCacheLock.RUnlock()
+
+	algorithms := make([]string, 0, len(SupportedAlgorithms))
+	for _, key := range publicJWKs {
+		if !slices.Contains(algorithms, key.Algorithm) {
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
+
+// generate a new key of the algorithm
+// the kid of an ed25519 key is the sha256 of the public key, the other keys use the jwk thumbprint (RFC 7638)
+func generateKey(algorithm jose.SignatureAlgorithm) (jose.JSONWebKey, jose.JSONWebKey, error) {
+	var publicKey, privateKey interface{}
+
+	switch algorithm {
+	case jose.EdDSA:
+		edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return jose.JSONWebKey{}, jose.JSONWebKey{}, err
+		}
+		publicKey, privateKey = edPublicKey, edPrivateKey
+	case jose.ES256:
+		ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return jose.JSONWebKey{}, jose.JSONWebKey{}, err
+		}
+		publicKey, privateKey = &ecPrivateKey.PublicKey, ecPrivateKey
+	case jose.RS256:
+		rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, consts.JWK_RSA_KEY_SIZE)
+		if err != nil {
+			return jose.JSONWebKey{}, jose.JSONWebKey{}, err
+		}
+		publicKey, privateKey = &rsaPrivateKey.PublicKey, rsaPrivateKey
+	default:
+		return jose.JSONWebKey{}, jose.JSONWebKey{}, errors.New("unsupported signing algorithm: " + string(algorithm))
+	}
+
+	publicJWK := jose.JSONWebKey{Key: publicKey, Algorithm: string(algorithm), Use: "sig"}
+
+	var keyThumbprint string
+	if edPublicKey, ok := publicKey.(ed25519.PublicKey); ok {
+		hasher := sha256.New()
+		hasher.Write(edPublicKey)
+		keyThumbprint = hex.EncodeToString(hasher.Sum(nil))
+	} else {
+		thumbprint, err := publicJWK.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return jose.JSONWebKey{}, jose.JSONWebKey{}, err
+		}
+		keyThumbprint = hex.EncodeToString(thumbprint)
+	}
+
+	publicJWK.KeyID = keyThumbprint
+	privateJWK := jose.JSONWebKey{Key: privateKey, KeyID: keyThumbprint, Algorithm: string(algorithm), Use: "sig"}
+
+	return publicJWK, privateJWK, nil
+}
@@ -1,69 +1,49 @@
 package jwkmanager
 
 import (
-	"crypto/ed25519"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"errors"
-	"gin-auth-mongo/utils/consts"
 	"log"
 	mathRand "math/rand"
-	"os"
 	"sync"
 
 	"github.com/square/go-jose/v3"
 )
 
 // global variables for storing keys and locks
+// the private keys are the pending and active keys, the public keys also contain the retired keys
 var (
-	privateJWKs  []jose.JSONWebKey
-	publicJWKs   []jose.JSONWebKey
-	keyCacheLock sync.RWMutex
+	privateJWKs   []jose.JSONWebKey
+	publicJWKs    []jose.JSONWebKey
+	keyStates     []KeyState
+	keySetVersion int64
+	keyCacheLock  sync.RWMutex
 )
 
-// load signing keys from file to memory
-func LoadSigningKeys(publicKeysFilePath, privateKeysFilePath string) error {
-	keyCacheLock.Lock()
-	defer keyCacheLock.Unlock()
-
-	// check file exists
-	if _, err := os.Stat(publicKeysFilePath); os.IsNotExist(err) {
-		return errors.New(publicKeysFilePath + " not found")
-	}
-
-	data, err := os.ReadFile(publicKeysFilePath)
+// load signing keys and their states from the key store to memory
+func LoadSigningKeys() error {
+	keySet, err := getKeyStore().Load()
 	if err != nil {
 		return err
 	}
 
-	var publicKeys []jose.JSONWebKey
-	if err := json.Unmarshal(data, &publicKeys); err != nil {
-		return err
-	}
-
-	publicJWKs = publicKeys
-	log.Println("Successfully loaded ", publicKeysFilePath, " keys into memory")
-
-	if _, err := os.Stat(privateKeysFilePath); os.IsNotExist(err) {
-		return errors.New(privateKeysFilePath + " not found")
-	}
+	setKeySet(keySet)
+	log.Printf("Successfully loaded %d public keys and %d private keys into memory, version %d", len(keySet.PublicKeys), len(keySet.PrivateKeys), keySet.Version)
 
-	data, err = os.ReadFile(privateKeysFilePath)
-	if err != nil {
-		return err
-	}
+	return nil
+}
 
-	var privateKeys []jose.JSONWebKey
-	if err := json.Unmarshal(data, &privateKeys); err != nil {
-		return err
-	}
+// replace the keys in memory
+func setKeySet(keySet *KeySet) {
+	normalizeAlgorithms(keySet.PrivateKeys)
+	normalizeAlgorithms(keySet.PublicKeys)
 
-	privateJWKs = privateKeys
-	log.Println("Successfully loaded ", privateKeysFilePath, " keys into memory")
+	keyCacheLock.Lock()
+	defer keyCacheLock.Unlock()
 
-	return nil
+	privateJWKs = keySet.PrivateKeys
+	publicJWKs = keySet.PublicKeys
+	keyStates = keySet.States
+	keySetVersion = keySet.Version
 }
 
 // get public JWKs
@@ -89,18 +69,43 @@ func GetPrivateJWKs() ([]jose.JSONWebKey, error) {
 	return privateJWKs, nil
 }
 
-// get random JWK
+// get random JWK, only the active keys are used for signing
 func GetRandomJWK() (jose.JSONWebKey, error) {
 
 	// read lock
 	keyCacheLock.RLock()
 	defer keyCacheLock.RUnlock()
 
-	if len(privateJWKs) == 0 {
+	activeJWKs := make([]jose.JSONWebKey, 0, len(privateJWKs))
+	for _, key := range privateJWKs {
+		if keyStatus(key.KeyID) == KeyStatusActive {
+			activeJWKs = append(activeJWKs, key)
+		}
+	}
+
+	if len(activeJWKs) == 0 {
 		return jose.JSONWebKey{}, errors.New("no signing keys found")
 	}
 
-	return privateJWKs[mathRand.Intn(len(privateJWKs))], nil
+	return activeJWKs[mathRand.Intn(len(activeJWKs))], nil
+}
+
+// get the public key to verify a JWT, the alg in the header MUST be the alg of the key
+// so a token can not be verified with an algorithm the key was not generated for
+func GetVerificationKey(kid string, alg string) (jose.JSONWebKey, error) {
+	keyCacheLock.RLock()
+	defer keyCacheLock.RUnlock()
+
+	for _, key := range publicJWKs {
+		if key.KeyID != kid {
+			continue
+		}
+		if string(KeyAlgorithm(key)) != alg {
+			return jose.JSONWebKey{}, errors.New("algorithm mismatch")
+		}
+		return key, nil
+	}
+	return jose.JSONWebKey{}, errors.New("key not found")
 }
 
 // get key by kid (for verifying JWT)
@@ -116,77 +121,21 @@ func GetKeyByID(kid string) (jose.JSONWebKey, error) {
 	return jose.JSONWebKey{}, errors.New("key not found")
 }
 
-// clear key files
-func clearKeyFiles(privateFilePath, publicFilePath string) {
-	if _, err := os.Stat(privateFilePath); err == nil {
-		e := os.Remove(privateFilePath)
-		if e != nil {
-			log.Fatal("Error removing ", privateFilePath, ":", e)
-		}
-	}
-	if _, err := os.Stat(publicFilePath); err == nil {
-		e := os.Remove(publicFilePath)
-		if e != nil {
-			log.Fatal("Error removing ", publicFilePath, ":", e)
-		}
-	}
-}
-
-// update keys
-func UpdateKeys() error {
-	privateFilePath := consts.PRIVATE_KEYS_FILE
-	publicFilePath := consts.PUBLIC_KEYS_FILE
-
-	// clear old key files
-	clearKeyFiles(privateFilePath, publicFilePath)
-
-	// new jwks
-	newPrivateJWKs := make([]jose.JSONWebKey, 0)
-	newPublicJWKs := make([]jose.JSONWebKey, 0)
+// save the keys and their states to the key store, then replace the keys in memory
+// ErrKeySetConflict is returned if another instance has changed the keys since they were loaded
+func saveKeys(newPrivateJWKs, newPublicJWKs []jose.JSONWebKey, newKeyStates []KeyState, version int64) error {
 
-	// generate 5 new jwks
-	for i := 0; i < 5; i++ {
-		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
-		if err != nil {
-			panic(err)
-		}
-
-		hasher := sha256.New()
-		hasher.Write(publicKey)
-		keyThumbprint := hex.EncodeToString(hasher.Sum(nil))
-
-		publicJWK := jose.JSONWebKey{Key: publicKey, KeyID: keyThumbprint, Algorithm: "Ed25519", Use: "sig"}
-		newPublicJWKs = append(newPublicJWKs, publicJWK)
-
-		privateJWK := jose.JSONWebKey{Key: privateKey, KeyID: keyThumbprint, Algorithm: "Ed25519", Use: "sig"}
-		newPrivateJWKs = append(newPrivateJWKs, privateJWK)
-	}
-
-	jsonData, err := json.Marshal(newPrivateJWKs)
-	if err != nil {
-		panic(err)
-	}
-	os.Mkdir(".private", os.ModePerm)
-	if err := os.WriteFile(privateFilePath, jsonData, 0644); err != nil {
-		panic(err)
+	keySet := &KeySet{
+		PrivateKeys: newPrivateJWKs,
+		PublicKeys:  newPublicJWKs,
+		States:      newKeyStates,
+		Version:     version,
 	}
 
-	jsonData, err = json.Marshal(newPublicJWKs)
-	if err != nil {
-		panic(err)
-	}
-	os.Mkdir(".public", os.ModePerm)
-	if err := os.WriteFile(publicFilePath, jsonData, 0644); err != nil {
-		panic(err)
-	}
-
-	privateJWKs = newPrivateJWKs
-	publicJWKs = newPublicJWKs
-
-	err = LoadSigningKeys(publicFilePath, privateFilePath)
-	if err != nil {
+	if err := getKeyStore().Save(keySet); err != nil {
 		return err
 	}
 
+	setKeySet(keySet)
 	return nil
 }
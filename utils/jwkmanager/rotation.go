@@ -0,0 +1,269 @@
+package jwkmanager
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/square/go-jose/v3"
+)
+
+// the lifecycle of a signing key: pending -> active -> retired -> removed
+// pending: published in the jwks so the resource servers can cache it, not used for signing yet
+// active: used for signing
+// retired: no longer used for signing, still published until the tokens signed with it have expired
+// removed: deleted from the key store
+type KeyStatus string
+
+const (
+	KeyStatusPending KeyStatus = "pending"
+	KeyStatusActive  KeyStatus = "active"
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+type KeyState struct {
+	KeyID       string    `json:"kid"`
+	Algorithm   string    `json:"alg,omitempty"`
+	Status      KeyStatus `json:"status"`
+	CreatedAt   string    `json:"createdAt"`
+	ActivatedAt string    `json:"activatedAt,omitempty"`
+	RetiredAt   string    `json:"retiredAt,omitempty"`
+}
+
+// serialize the rotations, the key set is read and written as a whole
+var rotationLock sync.Mutex
+
+func newKeyState(key jose.JSONWebKey, status KeyStatus) KeyState {
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	state := KeyState{KeyID: key.KeyID, Algorithm: string(KeyAlgorithm(key)), Status: status, CreatedAt: now}
+	if status == KeyStatusActive {
+		state.ActivatedAt = now
+	}
+	return state
+}
+
+// get the status of the key, the caller MUST hold keyCacheLock
+func keyStatus(kid string) KeyStatus {
+	for _, state := range keyStates {
+		if state.KeyID == kid {
+			return state.Status
+		}
+	}
+	return ""
+}
+
+func parseTime(t string) time.Time {
+	parsed, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, t, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
+
+// get a copy of the key states
+func GetKeyStates() []KeyState {
+	keyCacheLock.RLock()
+	defer keyCacheLock.RUnlock()
+
+	states := make([]KeyState, len(keyStates))
+	copy(states, keyStates)
+	return states
+}
+
+// get a copy of the keys, the states and the version in memory
+func snapshot() ([]jose.JSONWebKey, []jose.JSONWebKey, []KeyState, int64) {
+	keyCacheLock.RLock()
+	defer keyCacheLock.RUnlock()
+
+	privates := make([]jose.JSONWebKey, len(privateJWKs))
+	copy(privates, privateJWKs)
+	publics := make([]jose.JSONWebKey, len(publicJWKs))
+	copy(publics, publicJWKs)
+	states := make([]KeyState, len(keyStates))
+	copy(states, keyStates)
+	return privates, publics, states, keySetVersion
+}
+
+// save the keys, on a conflict the keys saved by the other instance are loaded instead
+func saveOrReload(privates, publics []jose.JSONWebKey, states []KeyState, version int64) error {
+	err := saveKeys(privates, publics, states, version)
+	if errors.Is(err, ErrKeySetConflict) {
+		if loadErr := LoadSigningKeys(); loadErr != nil {
+			return loadErr
+		}
+	}
+	return err
+}
+
+// drop the private keys of the keys which are not pending or active
+func dropPrivateKeys(privates []jose.JSONWebKey, states []KeyState) []jose.JSONWebKey {
+	kept := make([]jose.JSONWebKey, 0, len(privates))
+	for _, key := range privates {
+		for _, state := range states {
+			if state.KeyID == key.KeyID && (state.Status == KeyStatusPending || state.Status == KeyStatusActive) {
+				kept = append(kept, key)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// retire the active keys, the caller MUST drop their private keys
+func retireActiveKeys(states []KeyState, now string) {
+	for i := range states {
+		if states[i].Status == KeyStatusActive {
+			states[i].Status = KeyStatusRetired
+			states[i].RetiredAt = now
+		}
+	}
+}
+
+// remove the pending keys, nothing has been signed with them
+func removePendingKeys(states []KeyState, publics []jose.JSONWebKey) ([]KeyState, []jose.JSONWebKey) {
+	pendingKids := map[string]bool{}
+	keptStates := make([]KeyState, 0, len(states))
+	for _, state := range states {
+		if state.Status == KeyStatusPending {
+			pendingKids[state.KeyID] = true
+			continue
+		}
+		keptStates = append(keptStates, state)
+	}
+
+	keptPublics := make([]jose.JSONWebKey, 0, len(publics))
+	for _, key := range publics {
+		if !pendingKids[key.KeyID] {
+			keptPublics = append(keptPublics, key)
+		}
+	}
+	return keptStates, keptPublics
+}
+
+// generate new keys of the algorithm, the default algorithm is used if empty
+// the new keys are pending and replace the active keys in AdvanceKeys after consts.JWK_PENDING_PERIOD
+// if immediate, eg: on the first start or when a key is compromised, the new keys are active at once
+// to migrate to another algorithm, rotate to it, the keys of the old algorithm are published until they are removed
+func RotateKeys(immediate bool, algorithm jose.SignatureAlgorithm) error {
+	if algorithm == "" {
+		defaultAlgorithm, err := DefaultAlgorithm()
+		if err != nil {
+			return err
+		}
+		algorithm = defaultAlgorithm
+	}
+
+	rotationLock.Lock()
+	defer rotationLock.Unlock()
+
+	privates, publics, states, version := snapshot()
+
+	if !immediate {
+		for _, state := range states {
+			if state.Status == KeyStatusPending {
+				return errors.New("a key rotation is already pending")
+			}
+		}
+	}
+
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	if immediate {
+		retireActiveKeys(states, now)
+		states, publics = removePendingKeys(states, publics)
+	}
+
+	status := KeyStatusPending
+	if immediate {
+		status = KeyStatusActive
+	}
+
+	for i := 0; i < consts.JWK_ROTATION_KEY_COUNT; i++ {
+		publicJWK, privateJWK, err := generateKey(algorithm)
+		if err != nil {
+			return err
+		}
+		publics = append(publics, publicJWK)
+		privates = append(privates, privateJWK)
+		states = append(states, newKeyState(privateJWK, status))
+	}
+
+	err := saveOrReload(dropPrivateKeys(privates, states), publics, states, version)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Rotated jwk keys, %d new %s %s keys", consts.JWK_ROTATION_KEY_COUNT, status, algorithm)
+	return nil
+}
+
+// move the keys along the lifecycle, run periodically
+// pending keys older than consts.JWK_PENDING_PERIOD become active and the previous active keys are retired
+// retired keys older than the refresh token lifetime are removed
+func AdvanceKeys() error {
+	rotationLock.Lock()
+	defer rotationLock.Unlock()
+
+	privates, publics, states, version := snapshot()
+
+	now := time.Now()
+	nowString := now.Format(consts.DATETIME_NANO_FORMAT)
+	changed := false
+
+	promote := false
+	for _, state := range states {
+		if state.Status == KeyStatusPending && now.Sub(parseTime(state.CreatedAt)) >= time.Duration(consts.JWK_PENDING_PERIOD)*time.Minute {
+			promote = true
+			break
+		}
+	}
+
+	if promote {
+		retireActiveKeys(states, nowString)
+		for i := range states {
+			if states[i].Status == KeyStatusPending {
+				states[i].Status = KeyStatusActive
+				states[i].ActivatedAt = nowString
+			}
+		}
+		changed = true
+	}
+
+	// the tokens signed with a retired key are expired after the max access token lifetime
+	// but an expired access token is still presented with its refresh token, see RefreshToken, so the key is kept as long as the refresh token
+	maxTokenLifetime := time.Duration(max(consts.JWT_ACCESS_TOKEN_EXPIRY, consts.OIDC_ACCESS_TOKEN_EXPIRY, consts.OIDC_ID_TOKEN_EXPIRY, consts.JWT_REFRESH_TOKEN_EXPIRY*24*60)) * time.Minute
+	keptStates := make([]KeyState, 0, len(states))
+	removedKids := map[string]bool{}
+	for _, state := range states {
+		if state.Status == KeyStatusRetired && now.Sub(parseTime(state.RetiredAt)) >= maxTokenLifetime {
+			removedKids[state.KeyID] = true
+			continue
+		}
+		keptStates = append(keptStates, state)
+	}
+
+	if len(removedKids) > 0 {
+		keptPublics := make([]jose.JSONWebKey, 0, len(publics))
+		for _, key := range publics {
+			if !removedKids[key.KeyID] {
+				keptPublics = append(keptPublics, key)
+			}
+		}
+		publics = keptPublics
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	err := saveOrReload(dropPrivateKeys(privates, keptStates), publics, keptStates, version)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Advanced jwk keys, promoted: %v, removed: %d", promote, len(removedKids))
+	return nil
+}
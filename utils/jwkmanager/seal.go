@@ -0,0 +1,206 @@
+package jwkmanager
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"gin-auth-mongo/utils/crypto"
+
+	"github.com/square/go-jose/v3"
+)
+
+// the private keys are sealed with envelope encryption before they are stored
+// a random data key encrypts the private keys, the key-encryption key (kek) encrypts the data key
+// the kek id is kept in the envelope so the kek can be rotated, the keys sealed with an old kek are re-sealed on load
+const sealedKeysVersion = 1
+
+type sealedKeys struct {
+	Version      int    `json:"version"`
+	KeyID        string `json:"kid"`
+	Algorithm    string `json:"alg"`
+	EncryptedKey string `json:"encryptedKey"`
+	Ciphertext   string `json:"ciphertext"`
+}
+
+type keyEncryptionKeys struct {
+	currentID string
+	keys      map[string]string
+}
+
+var (
+	keks     *keyEncryptionKeys
+	keksLock sync.Mutex
+)
+
+// load the key-encryption keys, MUST BE 32 characters
+// JWK_ENCRYPTION_KEY or the content of JWK_ENCRYPTION_KEY_FILE is the current kek, JWK_ENCRYPTION_KEY_ID is its id (default 1)
+// JWK_ENCRYPTION_OLD_KEYS (id:key,id:key) are the previous keks, only used to open the keys sealed before a kek rotation
+func loadKeyEncryptionKeys() (*keyEncryptionKeys, error) {
+	keksLock.Lock()
+	defer keksLock.Unlock()
+
+	if keks != nil {
+		return keks, nil
+	}
+
+	key := os.Getenv("JWK_ENCRYPTION_KEY")
+	if key == "" && os.Getenv("JWK_ENCRYPTION_KEY_FILE") != "" {
+		data, err := os.ReadFile(os.Getenv("JWK_ENCRYPTION_KEY_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		key = strings.TrimSpace(string(data))
+	}
+	if len(key) != 32 {
+		return nil, errors.New("JWK_ENCRYPTION_KEY or JWK_ENCRYPTION_KEY_FILE must be 32 characters")
+	}
+
+	currentID := os.Getenv("JWK_ENCRYPTION_KEY_ID")
+	if currentID == "" {
+		currentID = "1"
+	}
+
+	loaded := &keyEncryptionKeys{currentID: currentID, keys: map[string]string{currentID: key}}
+
+	for _, entry := range strings.Split(os.Getenv("JWK_ENCRYPTION_OLD_KEYS"), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		id, oldKey, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || len(oldKey) != 32 {
+			return nil, errors.New("JWK_ENCRYPTION_OLD_KEYS must be id:key pairs with 32 characters keys")
+		}
+		if id == currentID {
+			return nil, errors.New("JWK_ENCRYPTION_OLD_KEYS must not contain the current key id")
+		}
+		loaded.keys[id] = oldKey
+	}
+
+	keks = loaded
+	return keks, nil
+}
+
+// seal the private keys with a new data key
+func sealPrivateKeys(privateKeys []jose.JSONWebKey) (*sealedKeys, error) {
+	keys, err := loadKeyEncryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	plainText, err := json.Marshal(privateKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	cipherText, err := crypto.EncryptString(string(plainText), string(dataKey))
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := crypto.EncryptString(string(dataKey), keys.keys[keys.currentID])
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealedKeys{
+		Version:      sealedKeysVersion,
+		KeyID:        keys.currentID,
+		Algorithm:    "A256GCM",
+		EncryptedKey: encryptedKey,
+		Ciphertext:   cipherText,
+	}, nil
+}
+
+// open the sealed private keys, stale is true if they were sealed with an old kek and need to be re-sealed
+func openPrivateKeys(sealed *sealedKeys) (privateKeys []jose.JSONWebKey, stale bool, err error) {
+	if sealed.Version != sealedKeysVersion {
+		return nil, false, errors.New("unsupported sealed keys version")
+	}
+
+	keys, err := loadKeyEncryptionKeys()
+	if err != nil {
+		return nil, false, err
+	}
+
+	kek, ok := keys.keys[sealed.KeyID]
+	if !ok {
+		return nil, false, errors.New("unknown key-encryption key: " + sealed.KeyID)
+	}
+
+	dataKey, err := crypto.DecryptString(sealed.EncryptedKey, kek)
+	if err != nil {
+		return nil, false, err
+	}
+
+	plainText, err := crypto.DecryptString(sealed.Ciphertext, dataKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := json.Unmarshal([]byte(plainText), &privateKeys); err != nil {
+		return nil, false, err
+	}
+
+	return privateKeys, sealed.KeyID != keys.currentID, nil
+}
+
+// the private keys are stored either sealed (an object) or in plaintext (an array, before the keys were sealed)
+// stale is true if the keys need to be re-sealed
+func decodePrivateKeys(data []byte) (privateKeys []jose.JSONWebKey, stale bool, err error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") || trimmed == "null" {
+		if err := json.Unmarshal(data, &privateKeys); err != nil {
+			return nil, false, err
+		}
+		return privateKeys, true, nil
+	}
+
+	var sealed sealedKeys
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, false, err
+	}
+	return openPrivateKeys(&sealed)
+}
+
+// the key set as stored in the shared key stores, the private keys are sealed
+type storedKeySet struct {
+	PrivateKeys json.RawMessage   `json:"privateKeys"`
+	PublicKeys  []jose.JSONWebKey `json:"publicKeys"`
+	States      []KeyState        `json:"states"`
+}
+
+func marshalKeySet(set *KeySet) ([]byte, error) {
+	sealed, err := sealPrivateKeys(set.PrivateKeys)
+	if err != nil {
+		return nil, err
+	}
+	sealedData, err := json.Marshal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(storedKeySet{PrivateKeys: sealedData, PublicKeys: set.PublicKeys, States: set.States})
+}
+
+// stale is true if the private keys need to be re-sealed
+func unmarshalKeySet(data []byte) (*KeySet, bool, error) {
+	var stored storedKeySet
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, false, err
+	}
+
+	privateKeys, stale, err := decodePrivateKeys(stored.PrivateKeys)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &KeySet{PrivateKeys: privateKeys, PublicKeys: stored.PublicKeys, States: stored.States}, stale, nil
+}
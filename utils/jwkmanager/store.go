@@ -0,0 +1,155 @@
+package jwkmanager
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/square/go-jose/v3"
+)
+
+// the keys and their states, saved and loaded as a whole
+// the version is increased on every save, a save based on an older version is rejected
+// the private keys are sealed by the stores, see seal.go
+type KeySet struct {
+	PrivateKeys []jose.JSONWebKey
+	PublicKeys  []jose.JSONWebKey
+	States      []KeyState
+	Version     int64
+}
+
+var (
+	ErrKeySetNotFound = errors.New("key set not found")
+	ErrKeySetConflict = errors.New("key set has been changed by another instance")
+)
+
+// KeyStore is the backend where the keys are kept
+// the file store is for single node setups, the shared stores (mongo, redis) let multiple instances sign and verify with the same keys
+type KeyStore interface {
+	// load the key set, ErrKeySetNotFound if there are no keys yet
+	Load() (*KeySet, error)
+	// save the key set if the stored version still equals set.Version, then set.Version is the new version
+	// ErrKeySetConflict if the stored version has changed
+	Save(set *KeySet) error
+	// whether the other instances may change the keys
+	Shared() bool
+}
+
+var (
+	keyStore     KeyStore
+	keyStoreLock sync.RWMutex
+)
+
+// choose the key store by the env JWK_STORE: file (default), mongo or redis
+// the shared stores notify the other instances on every save so they reload the keys
+func InitKeyStore() {
+	// the private keys can not be stored or loaded without the key-encryption key
+	if _, err := loadKeyEncryptionKeys(); err != nil {
+		log.Fatalf("Error loading jwk encryption key: %v", err)
+	}
+
+	backend := os.Getenv("JWK_STORE")
+	if backend == "" {
+		backend = "file"
+	}
+
+	switch backend {
+	case "file":
+		SetKeyStore(NewFileKeyStore(consts.PRIVATE_KEYS_FILE, consts.PUBLIC_KEYS_FILE, consts.KEY_STATES_FILE))
+	case "mongo":
+		SetKeyStore(NewMongoKeyStore(consts.JWK_KEY_SET_COLLECTION))
+	case "redis":
+		SetKeyStore(NewRedisKeyStore(consts.JWK_KEY_SET_KEY))
+	default:
+		log.Fatalf("Unknown JWK_STORE: %s", backend)
+	}
+
+	if getKeyStore().Shared() {
+		go watchKeyChanges()
+	}
+
+	log.Printf("Using %s jwk key store", backend)
+}
+
+func SetKeyStore(store KeyStore) {
+	keyStoreLock.Lock()
+	defer keyStoreLock.Unlock()
+	keyStore = store
+}
+
+func getKeyStore() KeyStore {
+	keyStoreLock.RLock()
+	defer keyStoreLock.RUnlock()
+	if keyStore == nil {
+		return NewFileKeyStore(consts.PRIVATE_KEYS_FILE, consts.PUBLIC_KEYS_FILE, consts.KEY_STATES_FILE)
+	}
+	return keyStore
+}
+
+// re-seal the private keys of a shared key store, the keys are unchanged so a conflict means another instance has done it
+func resealKeySet(store KeyStore, keySet *KeySet) {
+	resealed := *keySet
+	err := store.Save(&resealed)
+	if err != nil && !errors.Is(err, ErrKeySetConflict) {
+		log.Printf("Error re-sealing the private keys: %v", err)
+		return
+	}
+	if err == nil {
+		log.Println("Re-sealed the private keys in the key store")
+		keySet.Version = resealed.Version
+	}
+}
+
+// tell the other instances the keys have changed, the message is the new version
+func notifyKeyChange(version int64) {
+	err := databases.RedisClient.Publish(databases.GetRedisContext(), consts.JWK_CHANGE_CHANNEL, strconv.FormatInt(version, 10)).Err()
+	if err != nil {
+		// the other instances still pick up the keys on the next periodic reload
+		log.Printf("Error publishing jwk key change: %v", err)
+	}
+}
+
+// reload the keys when another instance has changed them
+func watchKeyChanges() {
+	pubsub := databases.RedisClient.Subscribe(databases.GetRedisContext(), consts.JWK_CHANGE_CHANNEL)
+	defer pubsub.Close()
+
+	for message := range pubsub.Channel() {
+		version, err := strconv.ParseInt(message.Payload, 10, 64)
+		if err == nil && version <= currentVersion() {
+			continue
+		}
+
+		if err := LoadSigningKeys(); err != nil {
+			log.Printf("Error reloading jwk keys: %v", err)
+		}
+	}
+}
+
+func currentVersion() int64 {
+	keyCacheLock.RLock()
+	defer keyCacheLock.RUnlock()
+	return keySetVersion
+}
+
+// reload the keys if the shared store has a newer version, run periodically in case a notification was missed
+func SyncKeys() error {
+	if !getKeyStore().Shared() {
+		return nil
+	}
+
+	keySet, err := getKeyStore().Load()
+	if err != nil {
+		return err
+	}
+	if keySet.Version > currentVersion() {
+		setKeySet(keySet)
+		log.Printf("Synced jwk keys to version %d", keySet.Version)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package jwkmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/square/go-jose/v3"
+)
+
+// FileKeyStore keeps the keys in local files, only for single node setups
+// the version only lives in memory, nobody else writes the files
+type FileKeyStore struct {
+	PrivateKeysFile string
+	PublicKeysFile  string
+	KeyStatesFile   string
+}
+
+func NewFileKeyStore(privateKeysFile, publicKeysFile, keyStatesFile string) *FileKeyStore {
+	return &FileKeyStore{PrivateKeysFile: privateKeysFile, PublicKeysFile: publicKeysFile, KeyStatesFile: keyStatesFile}
+}
+
+func readJSONFile(filePath string, v interface{}) error {
+	// check file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return errors.New(filePath + " not found")
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// write the file atomically, the file is replaced so the permission is applied to the existing files as well
+func writeFile(filePath string, data []byte, perm os.FileMode) error {
+	dirPerm := os.FileMode(0755)
+	if perm&0077 == 0 {
+		dirPerm = 0700
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), dirPerm); err != nil {
+		return err
+	}
+
+	tmpFilePath := filePath + ".tmp"
+	if err := os.WriteFile(tmpFilePath, data, perm); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFilePath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpFilePath, filePath)
+}
+
+func writeJSONFile(filePath string, v interface{}, perm os.FileMode) error {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFile(filePath, jsonData, perm)
+}
+
+// the private keys are sealed and only readable by the owner
+func (s *FileKeyStore) writePrivateKeys(privateKeys []jose.JSONWebKey) error {
+	sealed, err := sealPrivateKeys(privateKeys)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(s.PrivateKeysFile, sealed, 0600)
+}
+
+func (s *FileKeyStore) Load() (*KeySet, error) {
+	if _, err := os.Stat(s.PrivateKeysFile); os.IsNotExist(err) {
+		return nil, ErrKeySetNotFound
+	}
+
+	var publicKeys []jose.JSONWebKey
+	if err := readJSONFile(s.PublicKeysFile, &publicKeys); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.PrivateKeysFile)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeys, stale, err := decodePrivateKeys(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the keys were written in plaintext or sealed with an old key-encryption key
+	if stale {
+		log.Println("Re-sealing the private keys in ", s.PrivateKeysFile)
+		if err := s.writePrivateKeys(privateKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	var states []KeyState
+	if err := readJSONFile(s.KeyStatesFile, &states); err != nil {
+		// the key files were created before the key lifecycle, all the private keys are active
+		log.Println("No key states found in ", s.KeyStatesFile, ", marking all the private keys as active")
+		states = make([]KeyState, 0, len(privateKeys))
+		for _, key := range privateKeys {
+			states = append(states, newKeyState(key, KeyStatusActive))
+		}
+		if err := writeJSONFile(s.KeyStatesFile, states, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	return &KeySet{PrivateKeys: privateKeys, PublicKeys: publicKeys, States: states, Version: currentVersion()}, nil
+}
+
+func (s *FileKeyStore) Save(set *KeySet) error {
+	if err := s.writePrivateKeys(set.PrivateKeys); err != nil {
+		return err
+	}
+	if err := writeJSONFile(s.PublicKeysFile, set.PublicKeys, 0644); err != nil {
+		return err
+	}
+	if err := writeJSONFile(s.KeyStatesFile, set.States, 0600); err != nil {
+		return err
+	}
+
+	set.Version++
+	return nil
+}
+
+func (s *FileKeyStore) Shared() bool {
+	return false
+}
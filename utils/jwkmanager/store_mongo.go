@@ -0,0 +1,95 @@
+package jwkmanager
+
+import (
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/utils/consts"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// the key set is a single document, the keys are serialized as json since jose.JSONWebKey has no bson encoding
+// the private keys are sealed in the json
+const mongoKeySetID = "signing_keys"
+
+type mongoKeySet struct {
+	ID        string `bson:"_id"`
+	Version   int64  `bson:"version"`
+	KeySet    string `bson:"key_set"`
+	UpdatedAt string `bson:"updated_at"`
+}
+
+// MongoKeyStore shares the keys between the instances through mongodb
+type MongoKeyStore struct {
+	Collection string
+}
+
+func NewMongoKeyStore(collection string) *MongoKeyStore {
+	return &MongoKeyStore{Collection: collection}
+}
+
+func (s *MongoKeyStore) Load() (*KeySet, error) {
+	var document mongoKeySet
+	err := databases.GetMongoCollection(s.Collection).FindOne(databases.GetMongoContext(), bson.M{"_id": mongoKeySetID}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrKeySetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keySet, stale, err := unmarshalKeySet([]byte(document.KeySet))
+	if err != nil {
+		return nil, err
+	}
+	keySet.Version = document.Version
+
+	if stale {
+		resealKeySet(s, keySet)
+	}
+	return keySet, nil
+}
+
+func (s *MongoKeyStore) Save(set *KeySet) error {
+	data, err := marshalKeySet(set)
+	if err != nil {
+		return err
+	}
+
+	collection := databases.GetMongoCollection(s.Collection)
+	ctx := databases.GetMongoContext()
+	now := time.Now().Format(consts.DATETIME_NANO_FORMAT)
+	version := set.Version + 1
+
+	if set.Version == 0 {
+		// the first instance to start creates the keys, the others get a duplicate key error
+		_, err := collection.InsertOne(ctx, mongoKeySet{ID: mongoKeySetID, Version: version, KeySet: string(data), UpdatedAt: now})
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrKeySetConflict
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": mongoKeySetID, "version": set.Version},
+			bson.M{"$set": bson.M{"version": version, "key_set": string(data), "updated_at": now}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrKeySetConflict
+		}
+	}
+
+	set.Version = version
+	notifyKeyChange(version)
+	return nil
+}
+
+func (s *MongoKeyStore) Shared() bool {
+	return true
+}
@@ -0,0 +1,85 @@
+package jwkmanager
+
+import (
+	"gin-auth-mongo/databases"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// compare the version and save the key set atomically
+// KEYS[1]: the key set, KEYS[2]: the version, ARGV[1]: the expected version, ARGV[2]: the key set
+var saveKeySetScript = redis.NewScript(`
+local version = tonumber(redis.call('GET', KEYS[2]) or '0')
+if version ~= tonumber(ARGV[1]) then
+	return -1
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return redis.call('INCR', KEYS[2])
+`)
+
+// RedisKeyStore shares the keys between the instances through redis
+// redis MUST be persistent, otherwise the keys are regenerated after a restart and all the tokens become invalid
+type RedisKeyStore struct {
+	Key string
+}
+
+func NewRedisKeyStore(key string) *RedisKeyStore {
+	return &RedisKeyStore{Key: key}
+}
+
+func (s *RedisKeyStore) versionKey() string {
+	return s.Key + ":version"
+}
+
+func (s *RedisKeyStore) Load() (*KeySet, error) {
+	ctx := databases.GetRedisContext()
+
+	// read the key set and the version together
+	pipe := databases.RedisClient.TxPipeline()
+	keySetCmd := pipe.Get(ctx, s.Key)
+	versionCmd := pipe.Get(ctx, s.versionKey())
+	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		return nil, ErrKeySetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keySet, stale, err := unmarshalKeySet([]byte(keySetCmd.Val()))
+	if err != nil {
+		return nil, err
+	}
+	keySet.Version, err = versionCmd.Int64()
+	if err != nil {
+		return nil, err
+	}
+
+	if stale {
+		resealKeySet(s, keySet)
+	}
+	return keySet, nil
+}
+
+func (s *RedisKeyStore) Save(set *KeySet) error {
+	data, err := marshalKeySet(set)
+	if err != nil {
+		return err
+	}
+
+	version, err := saveKeySetScript.Run(databases.GetRedisContext(), databases.RedisClient, []string{s.Key, s.versionKey()}, set.Version, string(data)).Int64()
+	if err != nil {
+		return err
+	}
+	if version < 0 {
+		return ErrKeySetConflict
+	}
+
+	set.Version = version
+	notifyKeyChange(version)
+	return nil
+}
+
+func (s *RedisKeyStore) Shared() bool {
+	return true
+}
@@ -13,8 +13,10 @@ import (
 	"gin-auth-mongo/repositories"
 
 	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
 
 	"gin-auth-mongo/utils/jwkmanager"
+	"gin-auth-mongo/utils/rbac"
 
 	"github.com/gin-gonic/gin"
 	"github.com/square/go-jose/v3"
@@ -31,7 +33,7 @@ func GenerateRefreshToken(n int) (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-// pick a random signing key from the ./private/keys.json file
+// pick a random active signing key, signed with the algorithm of the key
 func pickRandomSigningKey() (jose.JSONWebKey, jose.SigningKey, error) {
 
 	// load from memory
@@ -40,27 +42,47 @@ func pickRandomSigningKey() (jose.JSONWebKey, jose.SigningKey, error) {
 		return jose.JSONWebKey{}, jose.SigningKey{}, err
 	}
 
-	key := jose.SigningKey{Algorithm: jose.EdDSA, Key: privateJWK}
+	key := jose.SigningKey{Algorithm: jwkmanager.KeyAlgorithm(privateJWK), Key: privateJWK}
 	return privateJWK, key, nil
 }
 
-// generate public and private claims
-func generateClaims(user *models.User, privateJWK jose.JSONWebKey, key jose.SigningKey) (*jwt.Builder, time.Time, error) {
+// create a signer with the kid of the key in the header
+func newSigner(privateJWK jose.JSONWebKey, key jose.SigningKey) (jose.Signer, error) {
 
 	var signerOptions = jose.SignerOptions{}
 	signerOptions.WithType("JWT")
 	signerOptions.WithHeader("kid", privateJWK.KeyID)
 
-	rsaSigner, err := jose.NewSigner(key, &signerOptions)
+	return jose.NewSigner(key, &signerOptions)
+}
+
+// generate public and private claims
+// the fid claim binds the access token to its refresh token family, checked when refreshing
+func generateClaims(user *models.User, familyID string, privateJWK jose.JSONWebKey, key jose.SigningKey) (*jwt.Builder, time.Time, error) {
+
+	rsaSigner, err := newSigner(privateJWK, key)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
 	builder := jwt.Signed(rsaSigner)
 
+	// the jti identifies the token in the denylist when it is revoked
+	jti, err := GenerateRefreshToken(16)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// the permissions of the roles at the time of issuing, the access tokens are revoked when they change
+	permissions, err := rbac.GetUserPermissions(user)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
 	// public claims
 	issuedAt := time.Now()
 	publicClaims := jwt.Claims{
+		ID:      jti,
 		Issuer:  consts.JWT_ISSUER,
 		Subject: user.ID.Hex(),
 		// Audience:
@@ -70,7 +92,11 @@ func generateClaims(user *models.User, privateJWK jose.JSONWebKey, key jose.Sign
 
 	// private claims
 	privateClaims := map[string]interface{}{
-		"email": user.Email,
+		"sub_type":    consts.SUBJECT_TYPE_USER,
+		"email":       user.Email,
+		"fid":         familyID,
+		"roles":       user.Roles,
+		"permissions": permissions,
 		// YOU CAN ADD MORE PRIVATE CLAIMS HERE
 	}
 
@@ -79,8 +105,8 @@ func generateClaims(user *models.User, privateJWK jose.JSONWebKey, key jose.Sign
 	return &builder, issuedAt, nil
 }
 
-// generate access and refresh token, then save to database
-func GenerateToken(user *models.User, device string, builder jwt.Builder, issuedAt time.Time) (*model.Token, error) {
+// generate access and refresh token, then save the hash of the refresh token to database
+func GenerateToken(user *models.User, device string, familyID string, builder jwt.Builder, issuedAt time.Time) (*model.Token, error) {
 
 	accessToken, err := builder.CompactSerialize()
 	if err != nil {
@@ -92,7 +118,8 @@ func GenerateToken(user *models.User, device string, builder jwt.Builder, issued
 		return nil, err
 	}
 
-	err = repositories.CreateRefreshToken(user.ID.Hex(), refreshToken, issuedAt.AddDate(0, 0, consts.JWT_REFRESH_TOKEN_EXPIRY), device)
+	refreshTokenExpiry := issuedAt.AddDate(0, 0, consts.JWT_REFRESH_TOKEN_EXPIRY)
+	err = repositories.CreateRefreshToken(user.ID.Hex(), crypto.HashToken(refreshToken), familyID, refreshTokenExpiry, device)
 
 	if err != nil {
 		return nil, err
@@ -103,7 +130,7 @@ func GenerateToken(user *models.User, device string, builder jwt.Builder, issued
 		AccessToken:        accessToken,
 		AccessTokenExpiry:  issuedAt.Add(time.Duration(consts.JWT_ACCESS_TOKEN_EXPIRY) * time.Minute).Format(consts.DATETIME_NANO_FORMAT),
 		RefreshToken:       refreshToken,
-		RefreshTokenExpiry: issuedAt.Add(time.Duration(consts.JWT_REFRESH_TOKEN_EXPIRY) * time.Hour).Format(consts.DATETIME_NANO_FORMAT),
+		RefreshTokenExpiry: refreshTokenExpiry.Format(consts.DATETIME_NANO_FORMAT),
 		Device:             device,
 	}
 
@@ -118,43 +145,63 @@ func HandleLogin(user *models.User, device string) (*model.Token, error) {
 		return nil, err
 	}
 
-	builder, issuedAt, err := generateClaims(user, privateJWK, key)
+	// every login starts a new family of refresh tokens
+	familyID, err := GenerateRefreshToken(16)
 	if err != nil {
 		return nil, err
 	}
 
-	return GenerateToken(user, device, *builder, issuedAt)
+	builder, issuedAt, err := generateClaims(user, familyID, privateJWK, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return GenerateToken(user, device, familyID, *builder, issuedAt)
 }
 
-func GenerateNewAccessToken(builder jwt.Builder, issuedAt time.Time) (*model.AccessToken, error) {
+// generate the access token and the next refresh token of the family
+// the next refresh token keeps the expiry of the family, a login lasts at most consts.JWT_REFRESH_TOKEN_EXPIRY days
+func GenerateNewAccessToken(builder jwt.Builder, issuedAt time.Time, refreshToken *models.UserRefreshToken) (*model.AccessToken, error) {
 
 	accessToken, err := builder.CompactSerialize()
 	if err != nil {
 		return nil, err
 	}
 
+	newRefreshToken, err := GenerateRefreshToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repositories.CreateRotatedRefreshToken(refreshToken, crypto.HashToken(newRefreshToken))
+	if err != nil {
+		return nil, err
+	}
+
 	accessTokenExpiry := issuedAt.Add(time.Duration(consts.JWT_ACCESS_TOKEN_EXPIRY) * time.Minute).Format(consts.DATETIME_NANO_FORMAT)
 
 	return &model.AccessToken{
-		AccessToken:       accessToken,
-		AccessTokenExpiry: accessTokenExpiry,
+		AccessToken:        accessToken,
+		AccessTokenExpiry:  accessTokenExpiry,
+		RefreshToken:       newRefreshToken,
+		RefreshTokenExpiry: refreshToken.ExpiredAt,
 	}, nil
 }
 
-// use to refresh access token
-func HandleRefreshToken(user *models.User) (*model.AccessToken, error) {
+// use to refresh access token, the refresh token MUST have been rotated by the caller
+func HandleRefreshToken(user *models.User, refreshToken *models.UserRefreshToken) (*model.AccessToken, error) {
 
 	privateJWK, key, err := pickRandomSigningKey()
 	if err != nil {
 		return nil, err
 	}
 
-	builder, issuedAt, err := generateClaims(user, privateJWK, key)
+	builder, issuedAt, err := generateClaims(user, refreshToken.FamilyID, privateJWK, key)
 	if err != nil {
 		return nil, err
 	}
 
-	return GenerateNewAccessToken(*builder, issuedAt)
+	return GenerateNewAccessToken(*builder, issuedAt, refreshToken)
 }
 
 // parse part
@@ -184,6 +231,27 @@ func GetTokenFromHeader(c *gin.Context) (string, error) {
 	return token, nil
 }
 
+// get a claim which is an array of strings, eg: roles and permissions
+func ClaimStrings(claims map[string]interface{}, key string) []string {
+	values, _ := claims[key].([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// check if the claims are of an access token issued by this server to the user
+// the id tokens, the tokens of the oidc clients and of the service accounts are not for the user api
+func IsUserAccessToken(claims map[string]interface{}) bool {
+	if _, ok := claims["client_id"]; ok {
+		return false
+	}
+	return claims["iss"] == consts.JWT_ISSUER && claims["sub_type"] == consts.SUBJECT_TYPE_USER
+}
+
 // parse the jwt token and return the claims
 func ParseJWTClaims(token string) (map[string]interface{}, error) {
 	parsedJWT, err := ParseToken(token)
@@ -195,26 +263,15 @@ func ParseJWTClaims(token string) (map[string]interface{}, error) {
 		return nil, errors.New("no headers found")
 	}
 
-	// get public jwks
-	publicJWKs, err := jwkmanager.GetPublicJWKs()
+	// get the public jwk of the kid, the alg in the header MUST be the alg of the key
+	publicJWK, err := jwkmanager.GetVerificationKey(parsedJWT.Headers[0].KeyID, parsedJWT.Headers[0].Algorithm)
 	if err != nil {
-		return nil, err
-	}
-
-	// create jwks
-	JWKs := jose.JSONWebKeySet{
-		Keys: publicJWKs,
-	}
-	publicJWK := JWKs.Key(parsedJWT.Headers[0].KeyID)
-
-	// check if the public jwk is valid
-	if len(publicJWK) <= 0 {
 		return nil, errors.New("invalid public jwk")
 	}
 
 	// extract the claims
 	allClaims := make(map[string]interface{})
-	if err := parsedJWT.Claims(publicJWK[0].Key, &allClaims); err != nil {
+	if err := parsedJWT.Claims(publicJWK.Key, &allClaims); err != nil {
 		return nil, err
 	}
 
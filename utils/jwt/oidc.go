@@ -0,0 +1,88 @@
+package jwt
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/square/go-jose/v3/jwt"
+)
+
+// the issuer of the openid connect provider, it MUST be the url the discovery document is served under
+func OIDCIssuer() string {
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return os.Getenv("BACKEND_URL")
+}
+
+// sign the claims with a random signing key
+func signClaims(publicClaims jwt.Claims, privateClaims map[string]interface{}) (string, error) {
+
+	privateJWK, key, err := pickRandomSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := newSigner(privateJWK, key)
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.Signed(signer).Claims(publicClaims).Claims(privateClaims).CompactSerialize()
+}
+
+// generate the access token for an oidc client
+// the client_id claim tells it apart from the tokens of this server, see JWTAuthMiddleware
+func GenerateOIDCAccessToken(userID string, clientID string, scopes []string, issuedAt time.Time) (string, error) {
+
+	// the jti identifies the token in the denylist when it is revoked
+	jti, err := GenerateRefreshToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	publicClaims := jwt.Claims{
+		ID:       jti,
+		Issuer:   OIDCIssuer(),
+		Subject:  userID,
+		Audience: jwt.Audience{clientID},
+		IssuedAt: jwt.NewNumericDate(issuedAt),
+		Expiry:   jwt.NewNumericDate(issuedAt.Add(time.Duration(consts.OIDC_ACCESS_TOKEN_EXPIRY) * time.Minute)),
+	}
+
+	privateClaims := map[string]interface{}{
+		"sub_type":  consts.SUBJECT_TYPE_USER,
+		"client_id": clientID,
+		"scope":     strings.Join(scopes, " "),
+	}
+
+	return signClaims(publicClaims, privateClaims)
+}
+
+// generate the id token for an oidc client, userClaims depend on the granted scopes
+func GenerateIDToken(userID string, clientID string, nonce string, userClaims map[string]interface{}, issuedAt time.Time) (string, error) {
+
+	publicClaims := jwt.Claims{
+		Issuer:   OIDCIssuer(),
+		Subject:  userID,
+		Audience: jwt.Audience{clientID},
+		IssuedAt: jwt.NewNumericDate(issuedAt),
+		Expiry:   jwt.NewNumericDate(issuedAt.Add(time.Duration(consts.OIDC_ID_TOKEN_EXPIRY) * time.Minute)),
+	}
+
+	privateClaims := map[string]interface{}{}
+	for k, v := range userClaims {
+		privateClaims[k] = v
+	}
+	// the id token is not an access token, see IsUserAccessToken
+	privateClaims["client_id"] = clientID
+	privateClaims["token_use"] = consts.TOKEN_USE_ID
+	if nonce != "" {
+		privateClaims["nonce"] = nonce
+	}
+
+	return signClaims(publicClaims, privateClaims)
+}
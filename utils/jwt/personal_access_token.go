@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/account"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+)
+
+// generate a personal access token, the prefix tells it from a jwt in the authorization header
+func GeneratePersonalAccessToken() (string, error) {
+	token, err := GenerateRefreshToken(32)
+	if err != nil {
+		return "", err
+	}
+	return consts.PERSONAL_ACCESS_TOKEN_PREFIX + token, nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, consts.PERSONAL_ACCESS_TOKEN_PREFIX)
+}
+
+// get the personal access token if it is known and not expired, then record the usage
+// the usage is recorded at most once per consts.PERSONAL_ACCESS_TOKEN_LAST_USED_INTERVAL unless the ip changes
+func ValidatePersonalAccessToken(token string, ip string) (*models.UserPersonalAccessToken, error) {
+	personalAccessToken, err := repositories.GetPersonalAccessTokenByTokenHash(crypto.HashToken(token))
+	if err != nil || personalAccessToken == nil {
+		return nil, errors.New("invalid personal access token")
+	}
+
+	// the tokens of a user who is not active work again once the user is active
+	user, err := repositories.GetUserByID(personalAccessToken.UserID.Hex())
+	if err != nil || user == nil || account.CheckUserStatus(user) != nil {
+		return nil, errors.New("invalid personal access token")
+	}
+
+	now := time.Now()
+
+	if personalAccessToken.ExpiredAt != "" {
+		expiredAt, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, personalAccessToken.ExpiredAt, time.Local)
+		if err != nil || now.After(expiredAt) {
+			return nil, errors.New("personal access token expired")
+		}
+	}
+
+	lastUsedAt, err := time.ParseInLocation(consts.DATETIME_NANO_FORMAT, personalAccessToken.LastUsedAt, time.Local)
+	if err != nil || now.Sub(lastUsedAt) > consts.PERSONAL_ACCESS_TOKEN_LAST_USED_INTERVAL*time.Second || personalAccessToken.LastUsedIP != ip {
+		repositories.UpdatePersonalAccessTokenUsage(personalAccessToken.ID, ip)
+	}
+
+	return personalAccessToken, nil
+}
@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"strconv"
+	"time"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+)
+
+// revoke the access token before it expires, eg: on logout
+// the jti is kept in the denylist until the token expires
+func RevokeAccessToken(jti string, expiredAtUnix int64) error {
+	if jti == "" {
+		return nil
+	}
+
+	remaining := expiredAtUnix - time.Now().Unix()
+	if remaining <= 0 {
+		return nil
+	}
+
+	return databases.RedisSet(consts.JWT_DENYLIST+jti, "1", int(remaining), datetime.SECONDS)
+}
+
+// revoke all the access tokens of the user issued until now, eg: on logout from all devices
+// the watermark is kept until the last token issued before it expires
+func RevokeUserAccessTokens(userID string) error {
+	watermark := strconv.FormatInt(time.Now().Unix(), 10)
+	return databases.RedisSet(consts.JWT_USER_WATERMARK+userID, watermark, consts.JWT_ACCESS_TOKEN_EXPIRY, datetime.MINUTES)
+}
+
+// revoke all the access tokens of the service account issued until now, eg: it is disabled or its secret is rotated
+// the subjects are object ids, the service accounts share the watermarks with the users
+func RevokeServiceAccountAccessTokens(serviceAccountID string) error {
+	watermark := strconv.FormatInt(time.Now().Unix(), 10)
+	return databases.RedisSet(consts.JWT_USER_WATERMARK+serviceAccountID, watermark, consts.SERVICE_ACCOUNT_ACCESS_TOKEN_EXPIRY, datetime.MINUTES)
+}
+
+// revoke all the access tokens of the session, eg: the user signs out another device
+// the refresh tokens of the family MUST be deleted by the caller
+func RevokeFamilyAccessTokens(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	return databases.RedisSet(consts.JWT_FAMILY_DENYLIST+familyID, "1", consts.JWT_ACCESS_TOKEN_EXPIRY, datetime.MINUTES)
+}
+
+// check if the access token is in the denylist, belongs to a revoked session or issued before the watermark of the user
+// a token issued in the same second as the watermark is revoked as well, iat has a precision of seconds
+func IsAccessTokenRevoked(claims map[string]interface{}) (bool, error) {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		denied, err := databases.RedisExists(consts.JWT_DENYLIST + jti)
+		if err != nil {
+			return false, err
+		}
+		if denied {
+			return true, nil
+		}
+	}
+
+	if familyID, ok := claims["fid"].(string); ok && familyID != "" {
+		denied, err := databases.RedisExists(consts.JWT_FAMILY_DENYLIST + familyID)
+		if err != nil {
+			return false, err
+		}
+		if denied {
+			return true, nil
+		}
+	}
+
+	userID, _ := claims["sub"].(string)
+	watermark, err := databases.RedisGet(consts.JWT_USER_WATERMARK + userID)
+	if err != nil {
+		return false, err
+	}
+	if watermark == "" {
+		return false, nil
+	}
+
+	revokedBefore, err := strconv.ParseInt(watermark, 10, 64)
+	if err != nil {
+		return false, err
+	}
+
+	issuedAt, ok := claims["iat"].(float64)
+	if !ok {
+		return true, nil
+	}
+
+	return int64(issuedAt) <= revokedBefore, nil
+}
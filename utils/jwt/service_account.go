@@ -0,0 +1,37 @@
+package jwt
+
+import (
+	"strings"
+	"time"
+
+	"gin-auth-mongo/utils/consts"
+
+	"github.com/square/go-jose/v3/jwt"
+)
+
+// generate the access token of a service account, issued by the client credentials grant
+// the client_id claim keeps it out of the JWTAuthMiddleware, see ServiceAccountAuthMiddleware
+func GenerateServiceAccountAccessToken(serviceAccountID string, scopes []string, issuedAt time.Time) (string, error) {
+
+	// the jti identifies the token in the denylist when it is revoked
+	jti, err := GenerateRefreshToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	publicClaims := jwt.Claims{
+		ID:       jti,
+		Issuer:   consts.JWT_ISSUER,
+		Subject:  serviceAccountID,
+		IssuedAt: jwt.NewNumericDate(issuedAt),
+		Expiry:   jwt.NewNumericDate(issuedAt.Add(time.Duration(consts.SERVICE_ACCOUNT_ACCESS_TOKEN_EXPIRY) * time.Minute)),
+	}
+
+	privateClaims := map[string]interface{}{
+		"sub_type":  consts.SUBJECT_TYPE_SERVICE_ACCOUNT,
+		"client_id": serviceAccountID,
+		"scope":     strings.Join(scopes, " "),
+	}
+
+	return signClaims(publicClaims, privateClaims)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"gin-auth-mongo/utils/consts"
+	"html"
 	"io"
 	"log"
 	"os"
@@ -45,11 +46,21 @@ const (
 	VerificationFormTypeCode VerificationFormType = "CODE"
 )
 
+// what a second factor code is verified for, the user is told when a recovery code is used
+type SecondFactorPurpose string
+
+const (
+	SecondFactorPurposeLogin            SecondFactorPurpose = "LOGIN"
+	SecondFactorPurposeDisableTwoFactor SecondFactorPurpose = "DISABLE_TWO_FACTOR"
+)
+
 type VerificationRequestType string
 
 const (
 	VerificationRequestTypeRegister      VerificationRequestType = "REGISTER"
 	VerificationRequestTypeResetPassword VerificationRequestType = "RESET"
+	VerificationRequestTypeLogin         VerificationRequestType = "LOGIN"
+	VerificationRequestTypeBindPhone     VerificationRequestType = "BIND_PHONE"
 )
 
 func InitMail() {
@@ -136,14 +147,6 @@ func SendVerificationEmail(email string, username string, formType VerificationF
 		}
 	}
 
-	resultChan := make(chan error)
-	m := gomail.NewMessage()
-	SMTPFromAddress := os.Getenv("SMTP_FROM_ADDRESS")
-	SMTPFromName := os.Getenv("SMTP_FROM_NAME")
-	m.SetHeader("From", SMTPFromAddress)
-	m.SetHeader("To", email)
-	m.SetAddressHeader("Cc", SMTPFromAddress, SMTPFromName)
-	m.SetHeader("Subject", SMTPFromName+" Email Verification")
 	var content string
 
 	switch formType {
@@ -163,6 +166,20 @@ func SendVerificationEmail(email string, username string, formType VerificationF
 		}
 	}
 
+	return sendEmail(email, "Email Verification", content)
+}
+
+// send the html content to the email through the smtp worker and wait for the result
+func sendEmail(email string, subject string, content string) *SendResult {
+
+	resultChan := make(chan error)
+	m := gomail.NewMessage()
+	SMTPFromAddress := os.Getenv("SMTP_FROM_ADDRESS")
+	SMTPFromName := os.Getenv("SMTP_FROM_NAME")
+	m.SetHeader("From", SMTPFromAddress)
+	m.SetHeader("To", email)
+	m.SetAddressHeader("Cc", SMTPFromAddress, SMTPFromName)
+	m.SetHeader("Subject", SMTPFromName+" "+subject)
 	m.SetBody("text/html", content)
 
 	emailMessage := &EmailMessage{
@@ -178,12 +195,79 @@ func SendVerificationEmail(email string, username string, formType VerificationF
 	}
 }
 
+// warn the user that a recovery code was used to sign in
+func SendRecoveryCodeUsedEmail(email string, username string, purpose SecondFactorPurpose, remaining int) *SendResult {
+	if email == "" || username == "" {
+		return &SendResult{
+			Error: errors.New("invalid email or username"),
+		}
+	}
+
+	usedAt := time.Now().Format(consts.DATETIME_FORMAT)
+
+	var content string
+	switch purpose {
+	case SecondFactorPurposeLogin:
+		content = fmt.Sprintf(RecoveryCodeUsedTemplate, username, usedAt, remaining)
+	case SecondFactorPurposeDisableTwoFactor:
+		// the remaining codes are deleted with the two factor authentication
+		content = fmt.Sprintf(RecoveryCodeUsedToDisableTemplate, username, usedAt)
+	default:
+		return &SendResult{
+			Error: errors.New("invalid purpose"),
+		}
+	}
+	return sendEmail(email, "Security Alert", content)
+}
+
+// tell the user that the account is locked by too many failed logins, the link unlocks it at once
+func SendAccountLockedEmail(email string, username string, link string, expiry string) *SendResult {
+	if email == "" || username == "" || link == "" {
+		return &SendResult{
+			Error: errors.New("invalid email, username or link"),
+		}
+	}
+
+	content := fmt.Sprintf(AccountLockedTemplate, username, link, link, consts.LOGIN_LOCKOUT_EXPIRY, expiry)
+	return sendEmail(email, "Security Alert", content)
+}
+
+// tell the user that the account is suspended or banned, the reason is escaped as it is written by an admin
+func SendAccountStatusEmail(email string, username string, status string, reason string, until string) *SendResult {
+	if email == "" || username == "" {
+		return &SendResult{
+			Error: errors.New("invalid email or username"),
+		}
+	}
+
+	if reason == "" {
+		reason = "not given"
+	}
+	reason = html.EscapeString(reason)
+
+	var content string
+	switch status {
+	case consts.USER_STATUS_SUSPENDED:
+		content = fmt.Sprintf(AccountSuspendedTemplate, username, reason, until)
+	case consts.USER_STATUS_BANNED:
+		content = fmt.Sprintf(AccountBannedTemplate, username, reason)
+	default:
+		return &SendResult{
+			Error: errors.New("invalid status"),
+		}
+	}
+
+	return sendEmail(email, "Account Notice", content)
+}
+
 func GetVerificationLinkContent(email string, username string, requestType VerificationRequestType, link string, expiry string) string {
 	switch requestType {
 	case VerificationRequestTypeRegister:
 		return fmt.Sprintf(EmailRegisterLinkTemplate, username, link, link, consts.VERIFY_EMAIL_REGISTER_LINK_EXPIRY, expiry)
 	case VerificationRequestTypeResetPassword:
 		return fmt.Sprintf(PasswordResetLinkTemplate, username, link, link, consts.VERIFY_EMAIL_RESET_PWD_LINK_EXPIRY, expiry)
+	case VerificationRequestTypeLogin:
+		return fmt.Sprintf(EmailLoginLinkTemplate, username, link, link, consts.VERIFY_EMAIL_LOGIN_LINK_EXPIRY, expiry)
 	}
 	return ""
 }
@@ -194,6 +278,8 @@ func GetVerificationCodeContent(email string, username string, requestType Verif
 		return fmt.Sprintf(EmailRegisterCodeTemplate, username, code, consts.VERIFY_EMAIL_REGISTER_CODE_EXPIRY, expiry)
 	case VerificationRequestTypeResetPassword:
 		return fmt.Sprintf(PasswordResetCodeTemplate, username, code, consts.VERIFY_EMAIL_RESET_PWD_CODE_EXPIRY, expiry)
+	case VerificationRequestTypeLogin:
+		return fmt.Sprintf(EmailLoginCodeTemplate, username, code, consts.VERIFY_EMAIL_LOGIN_CODE_EXPIRY, expiry)
 	}
 	return ""
 }
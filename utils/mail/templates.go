@@ -38,3 +38,59 @@ var PasswordResetCodeTemplate string = `<h1>Password Reset</h1>
 <p>Expired time: %s</p>
 <p>This email is auto generated, please do not reply to this email.</p>
 <p>If you did not request this email, please ignore it.</p>`
+
+var EmailLoginLinkTemplate string = `<h1>Sign In</h1>
+<h2>Hello %s</h2>
+<p>You can sign in to your account by clicking the link below:</p>
+<a href="%s">%s</a>
+<p>This link will expire in <strong>%d minutes</strong> and can only be used once.</p>
+<p>Expired time: %s</p>
+<p>This email is auto generated, please do not reply to this email.</p>
+<p>If you did not request this email, please ignore it.</p>`
+
+var EmailLoginCodeTemplate string = `<h1>Sign In</h1>
+<h2>Hello %s</h2>
+<p>Here is your sign in verification code:</p>
+<h2>%s</h2>
+<p>This code will expire in <strong>%d minutes</strong>.</p>
+<p>Expired time: %s</p>
+<p>This email is auto generated, please do not reply to this email.</p>
+<p>If you did not request this email, please ignore it.</p>`
+
+var RecoveryCodeUsedTemplate string = `<h1>Security Alert</h1>
+<h2>Hello %s</h2>
+<p>A recovery code was used to sign in to your account.</p>
+<p>Used time: %s</p>
+<p>You have <strong>%d</strong> recovery codes remaining.</p>
+<p>If this was not you, please reset your password and regenerate your recovery codes immediately.</p>
+<p>This email is auto generated, please do not reply to this email.</p>`
+
+var RecoveryCodeUsedToDisableTemplate string = `<h1>Security Alert</h1>
+<h2>Hello %s</h2>
+<p>A recovery code was used to disable two factor authentication on your account.</p>
+<p>Used time: %s</p>
+<p>If this was not you, please reset your password and enable two factor authentication again immediately.</p>
+<p>This email is auto generated, please do not reply to this email.</p>`
+
+var AccountSuspendedTemplate string = `<h1>Account Suspended</h1>
+<h2>Hello %s</h2>
+<p>Your account has been suspended and you have been signed out of all your devices.</p>
+<p>Reason: %s</p>
+<p>You can sign in again after: <strong>%s</strong></p>
+<p>This email is auto generated, please do not reply to this email.</p>`
+
+var AccountBannedTemplate string = `<h1>Account Banned</h1>
+<h2>Hello %s</h2>
+<p>Your account has been banned and you have been signed out of all your devices.</p>
+<p>Reason: %s</p>
+<p>This email is auto generated, please do not reply to this email.</p>`
+
+var AccountLockedTemplate string = `<h1>Security Alert</h1>
+<h2>Hello %s</h2>
+<p>Your account has been locked after too many failed sign in attempts.</p>
+<p>If this was you, you can unlock your account by clicking the link below:</p>
+<a href="%s">%s</a>
+<p>Otherwise the account is unlocked automatically in <strong>%d minutes</strong>.</p>
+<p>Expired time: %s</p>
+<p>If this was not you, someone may be guessing your password, please consider changing it.</p>
+<p>This email is auto generated, please do not reply to this email.</p>`
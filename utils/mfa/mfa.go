@@ -0,0 +1,148 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/crypto"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// get the key used to encrypt the totp secrets, MUST BE 32 characters
+func getEncryptionKey() (string, error) {
+	key := os.Getenv("MFA_ENCRYPTION_KEY")
+	if len(key) != 32 {
+		return "", errors.New("MFA_ENCRYPTION_KEY must be 32 characters")
+	}
+	return key, nil
+}
+
+// generate a new totp secret for the account, return the raw secret and the otpauth uri
+func GenerateTOTPSecret(accountName string) (string, string, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      consts.MFA_TOTP_ISSUER,
+		AccountName: accountName,
+		Period:      consts.MFA_TOTP_PERIOD,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return key.Secret(), key.URL(), nil
+}
+
+// validate the totp code against the raw secret, allow one period of clock skew
+func ValidateTOTPCode(secret string, code string) bool {
+	_, valid := ValidateTOTPCodeStep(secret, code)
+	return valid
+}
+
+// validate the totp code against the raw secret and return the time step it was generated for, allow one period of clock skew
+// the caller can reject the codes of the steps already accepted, so a code can not be replayed within its window
+func ValidateTOTPCodeStep(secret string, code string) (int64, bool) {
+	now := time.Now()
+	for _, skew := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(skew*consts.MFA_TOTP_PERIOD) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, t, totp.ValidateOpts{
+			Period:    consts.MFA_TOTP_PERIOD,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return t.Unix() / consts.MFA_TOTP_PERIOD, true
+		}
+	}
+	return 0, false
+}
+
+// encrypt the totp secret before storing it
+func EncryptSecret(secret string) (string, error) {
+	key, err := getEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return crypto.EncryptString(secret, key)
+}
+
+// decrypt the stored totp secret
+func DecryptSecret(encryptedSecret string) (string, error) {
+	key, err := getEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return crypto.DecryptString(encryptedSecret, key)
+}
+
+// validate the totp code against the encrypted secret stored on the user
+func ValidateEncryptedTOTPCode(encryptedSecret string, code string) bool {
+	_, valid := ValidateEncryptedTOTPCodeStep(encryptedSecret, code)
+	return valid
+}
+
+// validate the totp code against the encrypted secret stored on the user and return its time step
+func ValidateEncryptedTOTPCodeStep(encryptedSecret string, code string) (int64, bool) {
+	secret, err := DecryptSecret(encryptedSecret)
+	if err != nil {
+		return 0, false
+	}
+	return ValidateTOTPCodeStep(secret, code)
+}
+
+// normalize the recovery code, eg: "ABCDE-12345 " -> "abcde12345"
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// generate single-use recovery codes, return the plain codes for the user and the hashes for storing
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, consts.MFA_RECOVERY_CODE_COUNT)
+	hashedCodes := make([]string, 0, consts.MFA_RECOVERY_CODE_COUNT)
+
+	for i := 0; i < consts.MFA_RECOVERY_CODE_COUNT; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+
+		hashedCode, err := crypto.HashPassword(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// format: xxxxx-xxxxx
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashedCodes = append(hashedCodes, hashedCode)
+	}
+
+	return codes, hashedCodes, nil
+}
+
+// find the hashed recovery code matching the code, return -1 if no match
+func MatchRecoveryCode(hashedCodes []string, code string) int {
+	code = normalizeRecoveryCode(code)
+	if len(code) != 10 {
+		return -1
+	}
+
+	for i, hashedCode := range hashedCodes {
+		match, err := crypto.VerifyPassword(code, hashedCode)
+		if err == nil && match {
+			return i
+		}
+	}
+	return -1
+}
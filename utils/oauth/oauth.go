@@ -0,0 +1,221 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+// ExternalUser is the user returned by the oauth provider
+type ExternalUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is an oauth2 provider with the authorization code flow
+// verifier is set for the oidc providers, the others fetch the user with fetchUser
+type Provider struct {
+	Name      string
+	Config    *oauth2.Config
+	verifier  *oidc.IDTokenVerifier
+	fetchUser func(ctx context.Context, client *http.Client) (*ExternalUser, error)
+}
+
+var providers = map[string]*Provider{}
+
+// init the providers from the environment variables, a provider without client id is disabled
+func InitOAuthProviders() {
+	ctx := context.Background()
+
+	if clientID := os.Getenv("OAUTH_GOOGLE_CLIENT_ID"); clientID != "" {
+		p, err := NewOIDCProvider(ctx, "google", "https://accounts.google.com", clientID, os.Getenv("OAUTH_GOOGLE_CLIENT_SECRET"))
+		if err != nil {
+			log.Printf("Error initializing oauth provider google: %v", err)
+		} else {
+			Register(p)
+		}
+	}
+
+	if clientID := os.Getenv("OAUTH_GITHUB_CLIENT_ID"); clientID != "" {
+		Register(NewGitHubProvider(clientID, os.Getenv("OAUTH_GITHUB_CLIENT_SECRET")))
+	}
+
+	if clientID := os.Getenv("OAUTH_OIDC_CLIENT_ID"); clientID != "" {
+		name := os.Getenv("OAUTH_OIDC_NAME")
+		if name == "" {
+			name = "oidc"
+		}
+		p, err := NewOIDCProvider(ctx, name, os.Getenv("OAUTH_OIDC_ISSUER"), clientID, os.Getenv("OAUTH_OIDC_CLIENT_SECRET"))
+		if err != nil {
+			log.Printf("Error initializing oauth provider %s: %v", name, err)
+		} else {
+			Register(p)
+		}
+	}
+}
+
+func Register(p *Provider) {
+	providers[p.Name] = p
+}
+
+func GetProvider(name string) (*Provider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, errors.New("unsupported oauth provider")
+	}
+	return p, nil
+}
+
+// the callback url registered at the provider
+func redirectURL(name string) string {
+	return os.Getenv("BACKEND_URL") + "/api/v1/auth/oauth/" + name + "/callback"
+}
+
+// create a provider from the oidc discovery document of the issuer
+func NewOIDCProvider(ctx context.Context, name, issuer, clientID, clientSecret string) (*Provider, error) {
+	oidcProvider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		Name: name,
+		Config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     oidcProvider.Endpoint(),
+			RedirectURL:  redirectURL(name),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: oidcProvider.Verifier(&oidc.Config{ClientID: clientID}),
+	}, nil
+}
+
+func NewGitHubProvider(clientID, clientSecret string) *Provider {
+	return &Provider{
+		Name: "github",
+		Config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     github.Endpoint,
+			RedirectURL:  redirectURL("github"),
+			Scopes:       []string{"read:user", "user:email"},
+		},
+		fetchUser: fetchGitHubUser,
+	}
+}
+
+// the url to redirect the user to, the challenge is derived from the pkce verifier
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.verifier != nil {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+	return p.Config.AuthCodeURL(state, opts...)
+}
+
+// exchange the authorization code and get the user from the provider
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*ExternalUser, error) {
+	token, err := p.Config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.verifier == nil {
+		return p.fetchUser(ctx, p.Config.Client(ctx, token))
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("id_token is missing")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid nonce")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &ExternalUser{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// github is not an oidc provider, the user and the primary verified email are fetched from the api
+func fetchGitHubUser(ctx context.Context, client *http.Client) (*ExternalUser, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(client, "https://api.github.com/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(client, "https://api.github.com/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	externalUser := &ExternalUser{
+		Subject: strconv.FormatInt(user.ID, 10),
+		Name:    user.Name,
+	}
+	if externalUser.Name == "" {
+		externalUser.Name = user.Login
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			externalUser.Email = e.Email
+			externalUser.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	return externalUser, nil
+}
@@ -0,0 +1,105 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"gin-auth-mongo/utils/oauth/oauthtest"
+
+	"golang.org/x/oauth2"
+)
+
+func newFakeProvider(t *testing.T) (*oauthtest.Provider, *Provider) {
+	t.Helper()
+
+	fake, err := oauthtest.NewProvider()
+	if err != nil {
+		t.Fatalf("start fake provider: %v", err)
+	}
+	t.Cleanup(fake.Close)
+
+	p, err := NewOIDCProvider(context.Background(), "fake", fake.Issuer(), "client-id", "client-secret")
+	if err != nil {
+		t.Fatalf("discover fake provider: %v", err)
+	}
+
+	return fake, p
+}
+
+// the parameters the provider receives from the authorization url
+func authorizationParams(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization url: %v", err)
+	}
+	return u.Query()
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	fake, p := newFakeProvider(t)
+
+	verifier := oauth2.GenerateVerifier()
+	params := authorizationParams(t, p.AuthCodeURL("state", "nonce", verifier))
+	if params.Get("code_challenge_method") != "S256" || params.Get("nonce") != "nonce" {
+		t.Fatalf("authorization url is missing pkce or nonce: %v", params)
+	}
+
+	code := fake.Authorize("client-id", params.Get("code_challenge"), params.Get("nonce"), oauthtest.User{
+		Subject:       "subject-1",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "Alice",
+	})
+
+	user, err := p.Exchange(context.Background(), code, "nonce", verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if user.Subject != "subject-1" || user.Email != "alice@example.com" || !user.EmailVerified || user.Name != "Alice" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+}
+
+func TestOIDCProviderExchangeNonceMismatch(t *testing.T) {
+	fake, p := newFakeProvider(t)
+
+	verifier := oauth2.GenerateVerifier()
+	params := authorizationParams(t, p.AuthCodeURL("state", "nonce", verifier))
+
+	// the id token carries the nonce of another authorization request
+	code := fake.Authorize("client-id", params.Get("code_challenge"), "another-nonce", oauthtest.User{Subject: "subject-1"})
+
+	if _, err := p.Exchange(context.Background(), code, "nonce", verifier); err == nil {
+		t.Fatal("expected the nonce mismatch to be rejected")
+	}
+}
+
+func TestOIDCProviderExchangePKCEMismatch(t *testing.T) {
+	fake, p := newFakeProvider(t)
+
+	params := authorizationParams(t, p.AuthCodeURL("state", "nonce", oauth2.GenerateVerifier()))
+	code := fake.Authorize("client-id", params.Get("code_challenge"), params.Get("nonce"), oauthtest.User{Subject: "subject-1"})
+
+	// the code is exchanged with the verifier of another authorization request
+	if _, err := p.Exchange(context.Background(), code, "nonce", oauth2.GenerateVerifier()); err == nil {
+		t.Fatal("expected the pkce mismatch to be rejected")
+	}
+}
+
+func TestOIDCProviderExchangeCodeReuse(t *testing.T) {
+	fake, p := newFakeProvider(t)
+
+	verifier := oauth2.GenerateVerifier()
+	params := authorizationParams(t, p.AuthCodeURL("state", "nonce", verifier))
+	code := fake.Authorize("client-id", params.Get("code_challenge"), params.Get("nonce"), oauthtest.User{Subject: "subject-1"})
+
+	if _, err := p.Exchange(context.Background(), code, "nonce", verifier); err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), code, "nonce", verifier); err == nil {
+		t.Fatal("expected the used code to be rejected")
+	}
+}
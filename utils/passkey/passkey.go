@@ -0,0 +1,149 @@
+package passkey
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"gin-auth-mongo/databases"
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/datetime"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var WebAuthn *webauthn.WebAuthn
+
+// init the relying party from the environment variables
+func InitWebAuthn() {
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	rpDisplayName := os.Getenv("WEBAUTHN_RP_DISPLAY_NAME")
+	rpOrigins := strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ",")
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: rpDisplayName,
+		RPOrigins:     rpOrigins,
+	})
+	if err != nil {
+		log.Fatalf("Error initializing webauthn: %v", err)
+	}
+
+	WebAuthn = w
+}
+
+// User wraps models.User and its passkeys to implement webauthn.User
+type User struct {
+	User        *models.User
+	Credentials []models.UserPasskeyCredential
+}
+
+// the user handle is the 12 bytes of the user id
+func (u *User) WebAuthnID() []byte {
+	id := u.User.ID
+	return id[:]
+}
+
+func (u *User) WebAuthnName() string {
+	return u.User.Email
+}
+
+func (u *User) WebAuthnDisplayName() string {
+	return u.User.Nickname
+}
+
+func (u *User) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, c := range u.Credentials {
+		credentials = append(credentials, ToWebAuthnCredential(c))
+	}
+	return credentials
+}
+
+// convert the stored credential to webauthn.Credential
+func ToWebAuthnCredential(c models.UserPasskeyCredential) webauthn.Credential {
+	credentialID, _ := DecodeCredentialID(c.CredentialID)
+
+	transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+	for _, t := range c.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+
+	return webauthn.Credential{
+		ID:              credentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: uint32(c.SignCount),
+		},
+	}
+}
+
+// convert webauthn.Credential to the stored credential
+func FromWebAuthnCredential(userID primitive.ObjectID, name string, c *webauthn.Credential) *models.UserPasskeyCredential {
+	transports := make([]string, 0, len(c.Transport))
+	for _, t := range c.Transport {
+		transports = append(transports, string(t))
+	}
+
+	return &models.UserPasskeyCredential{
+		UserID:          userID,
+		CredentialID:    EncodeCredentialID(c.ID),
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transports:      transports,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       int64(c.Authenticator.SignCount),
+		BackupEligible:  c.Flags.BackupEligible,
+		BackupState:     c.Flags.BackupState,
+		Name:            name,
+	}
+}
+
+func EncodeCredentialID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+func DecodeCredentialID(id string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(id)
+}
+
+// store the ceremony session in redis, it can only be used once
+func SaveSession(key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return databases.RedisSet(key, string(data), consts.PASSKEY_SESSION_EXPIRY, datetime.MINUTES)
+}
+
+// get and delete the ceremony session from redis
+func PopSession(key string) (*webauthn.SessionData, error) {
+	data, err := databases.RedisGet(key)
+	if err != nil || data == "" {
+		return nil, errors.New("invalid or expired passkey session")
+	}
+	databases.RedisDel(key)
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, errors.New("invalid or expired passkey session")
+	}
+	return &session, nil
+}
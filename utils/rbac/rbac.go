@@ -0,0 +1,63 @@
+package rbac
+
+import (
+	"slices"
+	"strings"
+
+	"gin-auth-mongo/models"
+	"gin-auth-mongo/repositories"
+	"gin-auth-mongo/utils/consts"
+)
+
+// check if the granted permissions contain the permission, the wildcards are expanded
+// eg: "*" grants everything, "users:*" grants "users:read" and "users:write"
+func HasPermission(granted []string, permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+	for _, p := range granted {
+		if p == permission || p == consts.PERMISSION_ALL || p == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// check if the permission can be put into a role, the wildcards of the known resources are allowed
+func IsValidPermission(permission string) bool {
+	if permission == consts.PERMISSION_ALL || slices.Contains(consts.PERMISSIONS, permission) {
+		return true
+	}
+	resource, action, _ := strings.Cut(permission, ":")
+	if action != "*" {
+		return false
+	}
+	for _, p := range consts.PERMISSIONS {
+		if strings.HasPrefix(p, resource+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// get the permissions of the roles of the user, the unknown roles are ignored
+func GetUserPermissions(user *models.User) ([]string, error) {
+	permissions := make([]string, 0)
+	if len(user.Roles) == 0 {
+		return permissions, nil
+	}
+
+	roles, err := repositories.GetRolesByNames(user.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	slices.Sort(permissions)
+
+	return permissions, nil
+}
@@ -0,0 +1,92 @@
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gin-auth-mongo/utils/consts"
+	"gin-auth-mongo/utils/mail"
+)
+
+// SMSSender sends a text message to a phone number in E.164 format
+type SMSSender interface {
+	Send(phone string, message string) error
+}
+
+var sender SMSSender
+
+// ConsoleSender prints the messages to the log, for development
+type ConsoleSender struct{}
+
+func (s *ConsoleSender) Send(phone string, message string) error {
+	log.Printf("[SMS] to %s: %s", phone, message)
+	return nil
+}
+
+// FileSender appends the messages to a file, for development and tests
+type FileSender struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *FileSender) Send(phone string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(consts.DATETIME_NANO_FORMAT), phone, message)
+	return err
+}
+
+// init the sms sender from the environment variables, default is console
+func InitSMS() {
+	switch os.Getenv("SMS_PROVIDER") {
+	case "file":
+		path := os.Getenv("SMS_FILE_PATH")
+		if path == "" {
+			path = "logs/sms.log"
+		}
+		sender = &FileSender{Path: path}
+	case "", "console":
+		sender = &ConsoleSender{}
+	default:
+		log.Fatalf("Unsupported SMS_PROVIDER: %s", os.Getenv("SMS_PROVIDER"))
+	}
+}
+
+// replace the sms sender, eg: a real provider
+func SetSender(s SMSSender) {
+	sender = s
+}
+
+func GetVerificationCodeContent(requestType mail.VerificationRequestType, code string, expiry string) string {
+	switch requestType {
+	case mail.VerificationRequestTypeBindPhone:
+		return fmt.Sprintf(PhoneVerifyCodeTemplate, code, consts.VERIFY_PHONE_CODE_EXPIRY, expiry)
+	case mail.VerificationRequestTypeLogin:
+		return fmt.Sprintf(PhoneLoginCodeTemplate, code, consts.VERIFY_PHONE_CODE_EXPIRY, expiry)
+	}
+	return ""
+}
+
+func SendVerificationSMS(phone string, requestType mail.VerificationRequestType, code string, expiry string) error {
+	if sender == nil {
+		return errors.New("sms sender is not initialized")
+	}
+
+	content := GetVerificationCodeContent(requestType, code, expiry)
+	if content == "" {
+		return errors.New("invalid request type")
+	}
+
+	return sender.Send(phone, content)
+}
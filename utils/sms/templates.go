@@ -0,0 +1,5 @@
+package sms
+
+const PhoneVerifyCodeTemplate = "[gin-auth-mongo] Your phone verification code is %s. It expires in %d minutes (%s)."
+
+const PhoneLoginCodeTemplate = "[gin-auth-mongo] Your sign in code is %s. It expires in %d minutes (%s). If you did not request it, please ignore this message."